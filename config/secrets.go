@@ -0,0 +1,58 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SecretRefPrefix marks a config value that names a stored secret, e.g.
+// "secret://discord.bot_token".
+const SecretRefPrefix = "secret://"
+
+// SecretResolver looks up a secret by name; *secrets.Store implements it.
+type SecretResolver interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// ResolveSecrets replaces secret:// references in credential fields
+// (ai.api_key, discord.bot_token and discord.webhooks) with their values.
+// Plain values are left as they are.
+func (c *Config) ResolveSecrets(ctx context.Context, r SecretResolver) error {
+	resolve := func(field string, v *string) error {
+		name, ok := strings.CutPrefix(*v, SecretRefPrefix)
+		if !ok {
+			return nil
+		}
+		value, err := r.Get(ctx, name)
+		if err != nil {
+			return fmt.Errorf("resolve %s: %w", field, err)
+		}
+		*v = value
+		return nil
+	}
+
+	if err := resolve("ai.api_key", &c.AI.APIKey); err != nil {
+		return err
+	}
+	if err := resolve("discord.bot_token", &c.Discord.BotToken); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(c.Discord.Webhooks))
+	for name := range c.Discord.Webhooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		url := c.Discord.Webhooks[name]
+		if err := resolve("discord.webhooks."+name, &url); err != nil {
+			return err
+		}
+		c.Discord.Webhooks[name] = url
+	}
+	return nil
+}
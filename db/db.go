@@ -7,8 +7,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -16,38 +19,109 @@ import (
 	"github.com/yourorg/arc-sdk/db/migrations"
 )
 
+// Pragma is a SQLite PRAGMA applied to every pooled connection.
+type Pragma struct {
+	Name  string
+	Value string
+}
+
+// DefaultPragmas are applied to every connection opened by Open.
+var DefaultPragmas = []Pragma{
+	// Busy timeout (ms)
+	{"busy_timeout", "5000"},
+	{"journal_mode", "WAL"},
+	// Recommended durability/perf balance
+	{"synchronous", "NORMAL"},
+	// Enforce FKs
+	{"foreign_keys", "ON"},
+	// Negative = kibibytes; 64MB
+	{"cache_size", "-64000"},
+	// Temp store in memory
+	{"temp_store", "MEMORY"},
+}
+
+// Options configures OpenWithOptions. The zero value matches Open.
+type Options struct {
+	// ReadOnly opens the database without write access and skips migrations.
+	ReadOnly bool
+	// SkipMigrations opens the database without applying embedded migrations.
+	SkipMigrations bool
+
+	// MaxOpenConns limits open connections (default 25).
+	MaxOpenConns int
+	// MaxIdleConns limits idle connections (default 5).
+	MaxIdleConns int
+	// ConnMaxLifetime bounds connection reuse (default 5m; ignored in memory).
+	ConnMaxLifetime time.Duration
+
+	// Pragmas override DefaultPragmas by name or add new ones.
+	Pragmas []Pragma
+	// TxLock sets how BEGIN acquires locks: "deferred", "immediate" or "exclusive".
+	TxLock string
+
+	// Tracer, if set, records every statement run through the handle.
+	Tracer *Tracer
+
+	// MemoryName names the shared-cache database used when path is ":memory:".
+	// Handles opened with the same name see the same data; empty picks a
+	// unique name so each Open gets a fresh database.
+	MemoryName string
+}
+
+const memoryPath = ":memory:"
+
+var memorySeq atomic.Int64
+
 // Open opens a SQLite database at the given path and applies embedded migrations.
 func Open(path string) (*sql.DB, error) {
-	dsn := path
-	if dsn == "" {
-		dsn = DefaultDBPath()
+	return OpenWithOptions(path, Options{})
+}
+
+// OpenWithOptions opens a SQLite database at the given path using opts.
+// Pragmas are passed through the DSN so every pooled connection gets them.
+func OpenWithOptions(path string, opts Options) (*sql.DB, error) {
+	if path == "" {
+		path = DefaultDBPath()
 	}
+	memory := path == memoryPath
 
-	// Ensure parent directory exists for file-backed databases
-	if dsn != ":memory:" {
-		if err := os.MkdirAll(filepath.Dir(dsn), 0o755); err != nil {
+	// Ensure parent directory exists for writable file-backed databases
+	if !memory && !opts.ReadOnly {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 			return nil, fmt.Errorf("create db dir: %w", err)
 		}
 	}
 
-	handle, err := sql.Open("sqlite", dsn)
+	dsn, err := buildDSN(path, opts)
 	if err != nil {
 		return nil, err
 	}
 
+	handle := sql.OpenDB(&tracedConnector{dsn: dsn, tracer: opts.Tracer})
+
 	// Connection pool limits suitable for CLI usage
-	handle.SetMaxOpenConns(25)
-	handle.SetMaxIdleConns(5)
-	handle.SetConnMaxLifetime(5 * time.Minute)
+	handle.SetMaxOpenConns(intOr(opts.MaxOpenConns, 25))
+	handle.SetMaxIdleConns(intOr(opts.MaxIdleConns, 5))
+	if memory {
+		// A shared-cache memory database disappears with its last connection.
+		handle.SetMaxIdleConns(max(intOr(opts.MaxIdleConns, 5), 1))
+		handle.SetConnMaxLifetime(0)
+	} else if opts.ConnMaxLifetime > 0 {
+		handle.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	} else {
+		handle.SetConnMaxLifetime(5 * time.Minute)
+	}
 
-	if err := applyPragmas(handle); err != nil {
+	if err := verifyJournalMode(handle, memory, opts); err != nil {
 		_ = handle.Close()
 		return nil, err
 	}
 
-	if err := migrations.RunMigrations(handle); err != nil {
-		_ = handle.Close()
-		return nil, err
+	if !opts.ReadOnly && !opts.SkipMigrations {
+		if err := migrations.RunMigrations(handle); err != nil {
+			_ = handle.Close()
+			return nil, err
+		}
 	}
 	return handle, nil
 }
@@ -66,34 +140,134 @@ func DefaultDBPath() string {
 	return filepath.Join(home, ".local", "share", "arc", "arc.db")
 }
 
-func applyPragmas(db *sql.DB) error {
-	// WAL mode; select returns the new mode
-	var mode string
-	if err := db.QueryRow("PRAGMA journal_mode = WAL;").Scan(&mode); err != nil {
-		return fmt.Errorf("set WAL: %w", err)
+// Pool pairs a single-connection writer with a multi-connection read-only
+// pool. Funnelling writes through one connection with BEGIN IMMEDIATE avoids
+// SQLITE_BUSY from lock upgrades when several commands share a database.
+type Pool struct {
+	Writer *sql.DB
+	Reader *sql.DB
+}
+
+// OpenPool opens a writer/reader pool pair for path. opts applies to both
+// handles; the writer is limited to one connection and the reader is read-only.
+func OpenPool(path string, opts Options) (*Pool, error) {
+	if path == memoryPath && opts.MemoryName == "" {
+		opts.MemoryName = nextMemoryName()
 	}
-	if mode != "wal" {
-		return errors.New("journal_mode not WAL")
+
+	wopts := opts
+	wopts.ReadOnly = false
+	wopts.MaxOpenConns = 1
+	wopts.MaxIdleConns = 1
+	if wopts.TxLock == "" {
+		wopts.TxLock = "immediate"
 	}
-	// Recommended durability/perf balance
-	if _, err := db.Exec("PRAGMA synchronous = NORMAL;"); err != nil {
-		return fmt.Errorf("set synchronous: %w", err)
+	writer, err := OpenWithOptions(path, wopts)
+	if err != nil {
+		return nil, err
 	}
-	// Enforce FKs
-	if _, err := db.Exec("PRAGMA foreign_keys = ON;"); err != nil {
-		return fmt.Errorf("enable foreign_keys: %w", err)
+
+	ropts := opts
+	ropts.ReadOnly = true
+	ropts.TxLock = ""
+	reader, err := OpenWithOptions(path, ropts)
+	if err != nil {
+		_ = writer.Close()
+		return nil, err
 	}
-	// Busy timeout (ms)
-	if _, err := db.Exec("PRAGMA busy_timeout = 5000;"); err != nil {
-		return fmt.Errorf("set busy_timeout: %w", err)
+	return &Pool{Writer: writer, Reader: reader}, nil
+}
+
+// Close closes both handles.
+func (p *Pool) Close() error {
+	return errors.Join(p.Reader.Close(), p.Writer.Close())
+}
+
+// mergePragmas returns DefaultPragmas with overrides applied by name.
+func mergePragmas(overrides []Pragma) []Pragma {
+	out := make([]Pragma, len(DefaultPragmas))
+	copy(out, DefaultPragmas)
+	for _, o := range overrides {
+		replaced := false
+		for i := range out {
+			if strings.EqualFold(out[i].Name, o.Name) {
+				out[i].Value = o.Value
+				replaced = true
+			}
+		}
+		if !replaced {
+			out = append(out, o)
+		}
 	}
-	// Negative = kibibytes; 64MB
-	if _, err := db.Exec("PRAGMA cache_size = -64000;"); err != nil {
-		return fmt.Errorf("set cache_size: %w", err)
+	return out
+}
+
+func buildDSN(path string, opts Options) (string, error) {
+	q := url.Values{}
+	var base string
+
+	if path == memoryPath {
+		name := opts.MemoryName
+		if name == "" {
+			name = nextMemoryName()
+		}
+		base = "file:" + url.PathEscape(name)
+		q.Set("mode", "memory")
+		q.Set("cache", "shared")
+	} else {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", fmt.Errorf("resolve db path: %w", err)
+		}
+		base = "file:" + (&url.URL{Path: filepath.ToSlash(abs)}).EscapedPath()
+		if opts.ReadOnly {
+			q.Set("mode", "ro")
+		}
 	}
-	// Temp store in memory
-	if _, err := db.Exec("PRAGMA temp_store = MEMORY;"); err != nil {
-		return fmt.Errorf("set temp_store: %w", err)
+
+	for _, p := range mergePragmas(opts.Pragmas) {
+		name := strings.ToLower(p.Name)
+		// The journal mode lives in the file; memory and read-only handles
+		// cannot (and need not) change it.
+		if name == "journal_mode" && (path == memoryPath || opts.ReadOnly) {
+			continue
+		}
+		q.Add("_pragma", fmt.Sprintf("%s(%s)", name, p.Value))
+	}
+	if opts.ReadOnly {
+		q.Add("_pragma", "query_only(1)")
+	}
+	if opts.TxLock != "" {
+		q.Set("_txlock", opts.TxLock)
+	}
+	return base + "?" + q.Encode(), nil
+}
+
+func verifyJournalMode(db *sql.DB, memory bool, opts Options) error {
+	want := ""
+	for _, p := range mergePragmas(opts.Pragmas) {
+		if strings.EqualFold(p.Name, "journal_mode") {
+			want = p.Value
+		}
+	}
+	// Querying journal_mode opens the database, applying the DSN pragmas.
+	var mode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		return fmt.Errorf("set journal_mode %s: %w", want, err)
+	}
+	if memory || opts.ReadOnly || want == "" || strings.EqualFold(want, mode) {
+		return nil
+	}
+	return fmt.Errorf("journal_mode is %s, want %s", mode, want)
+}
+
+func nextMemoryName() string {
+	return fmt.Sprintf("arc-mem-%d-%d", os.Getpid(), memorySeq.Add(1))
+}
+
+func intOr(v, def int) int {
+	if v > 0 {
+		return v
 	}
-	return nil
+	return def
 }
@@ -0,0 +1,105 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package db
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestOpenMemorySharedAcrossConnections(t *testing.T) {
+	handle, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer handle.Close()
+
+	if _, err := handle.Exec(`INSERT INTO settings(key, value) VALUES('k', 'v')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// Hold several connections at once; each must see the migrated schema and row.
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var v string
+			errs <- handle.QueryRow(`SELECT value FROM settings WHERE key = 'k'`).Scan(&v)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("query on pooled connection: %v", err)
+		}
+	}
+
+	other, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open second: %v", err)
+	}
+	defer other.Close()
+	var n int
+	if err := other.QueryRow(`SELECT COUNT(*) FROM settings`).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("second memory db should be empty, got %d (%v)", n, err)
+	}
+}
+
+func TestOpenWithOptionsReadOnlyAndPragmas(t *testing.T) {
+	path := t.TempDir() + "/opts.db"
+	rw, err := OpenWithOptions(path, Options{
+		MaxOpenConns: 2,
+		Pragmas:      []Pragma{{"busy_timeout", "1234"}, {"recursive_triggers", "ON"}},
+	})
+	if err != nil {
+		t.Fatalf("OpenWithOptions: %v", err)
+	}
+	defer rw.Close()
+
+	var timeout, recursive int
+	if err := rw.QueryRow(`PRAGMA busy_timeout`).Scan(&timeout); err != nil || timeout != 1234 {
+		t.Fatalf("busy_timeout = %d (%v), want 1234", timeout, err)
+	}
+	if err := rw.QueryRow(`PRAGMA recursive_triggers`).Scan(&recursive); err != nil || recursive != 1 {
+		t.Fatalf("recursive_triggers = %d (%v), want 1", recursive, err)
+	}
+
+	ro, err := OpenWithOptions(path, Options{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("OpenWithOptions read-only: %v", err)
+	}
+	defer ro.Close()
+	if _, err := ro.Exec(`INSERT INTO settings(key, value) VALUES('k', 'v')`); err == nil {
+		t.Fatalf("expected write on read-only handle to fail")
+	}
+
+	if _, err := OpenWithOptions(t.TempDir()+"/missing.db", Options{ReadOnly: true}); err == nil {
+		t.Fatalf("expected read-only open of missing file to fail")
+	}
+}
+
+func TestOpenPool(t *testing.T) {
+	for _, path := range []string{":memory:", t.TempDir() + "/pool.db"} {
+		pool, err := OpenPool(path, Options{})
+		if err != nil {
+			t.Fatalf("OpenPool(%s): %v", path, err)
+		}
+		if _, err := pool.Writer.Exec(`INSERT INTO settings(key, value) VALUES('k', 'v')`); err != nil {
+			t.Fatalf("writer insert: %v", err)
+		}
+		var v string
+		if err := pool.Reader.QueryRow(`SELECT value FROM settings WHERE key = 'k'`).Scan(&v); err != nil || v != "v" {
+			t.Fatalf("reader got %q (%v), want v", v, err)
+		}
+		if _, err := pool.Reader.Exec(`DELETE FROM settings`); err == nil {
+			t.Fatalf("expected reader write to fail")
+		}
+		if err := pool.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FTSTables lists the FTS5 indexes maintained by the arc schema.
+var FTSTables = []string{
+	"sessions_fts",
+	"external_repos_fts",
+	"papers_fts",
+	"repo_analysis_fts",
+}
+
+// pragmaNumbers maps the named values of enumerated pragmas to the numbers
+// their PRAGMA query reports.
+var pragmaNumbers = map[string]map[string]string{
+	"synchronous": {"off": "0", "normal": "1", "full": "2", "extra": "3"},
+	"temp_store":  {"default": "0", "file": "1", "memory": "2"},
+	"auto_vacuum": {"none": "0", "full": "1", "incremental": "2"},
+}
+
+// pragmaBools maps boolean pragma values to what their PRAGMA query reports.
+var pragmaBools = map[string]string{"on": "1", "true": "1", "yes": "1", "off": "0", "false": "0", "no": "0"}
+
+// pragmaReadback returns value as the PRAGMA query for name reports it, e.g.
+// synchronous NORMAL as "1".
+func pragmaReadback(name, value string) string {
+	name = strings.ToLower(name)
+	value = strings.ToLower(strings.TrimSpace(value))
+	if names, ok := pragmaNumbers[name]; ok {
+		if n, ok := names[value]; ok {
+			return n
+		}
+		return value
+	}
+	if name == "journal_mode" || name == "locking_mode" {
+		return value
+	}
+	if n, ok := pragmaBools[value]; ok {
+		return n
+	}
+	return value
+}
+
+// DoctorOptions controls which checks Doctor runs.
+type DoctorOptions struct {
+	// Quick uses PRAGMA quick_check instead of the slower integrity_check.
+	Quick bool
+	// SkipSizes skips the per-table row counts and page sizes.
+	SkipSizes bool
+	// Pragmas and ReadOnly are the Options the handle was opened with.
+	// Pragmas are checked against DefaultPragmas merged with the overrides;
+	// journal_mode is not checked for read-only or in-memory databases.
+	Pragmas  []Pragma
+	ReadOnly bool
+}
+
+// PragmaCheck compares a pragma's current value with the expected one.
+type PragmaCheck struct {
+	Name     string
+	Expected string
+	Actual   string
+	OK       bool
+}
+
+// ForeignKeyViolation is a row reported by PRAGMA foreign_key_check.
+type ForeignKeyViolation struct {
+	Table  string
+	RowID  int64
+	Parent string
+	FKID   int
+}
+
+// FTSCheck reports the consistency of a single FTS5 index.
+type FTSCheck struct {
+	Table   string
+	Exists  bool
+	OK      bool
+	Rows    int64
+	Message string
+}
+
+// TableStats reports row count and on-disk size for a table.
+type TableStats struct {
+	Name  string
+	Rows  int64
+	Bytes int64
+}
+
+// DoctorReport is the result of a database health check.
+type DoctorReport struct {
+	Integrity   []string
+	ForeignKeys []ForeignKeyViolation
+	Pragmas     []PragmaCheck
+	FTS         []FTSCheck
+	Tables      []TableStats
+	PageSize    int64
+	PageCount   int64
+	FreePages   int64
+}
+
+// Healthy reports whether every check passed.
+func (r *DoctorReport) Healthy() bool {
+	return len(r.Problems()) == 0
+}
+
+// Problems returns a human-readable list of failed checks.
+func (r *DoctorReport) Problems() []string {
+	var out []string
+	for _, msg := range r.Integrity {
+		if msg != "ok" {
+			out = append(out, "integrity: "+msg)
+		}
+	}
+	for _, v := range r.ForeignKeys {
+		out = append(out, fmt.Sprintf("foreign key: %s row %d references missing %s", v.Table, v.RowID, v.Parent))
+	}
+	for _, p := range r.Pragmas {
+		if !p.OK {
+			out = append(out, fmt.Sprintf("pragma %s = %s, want %s", p.Name, p.Actual, p.Expected))
+		}
+	}
+	for _, f := range r.FTS {
+		if f.Exists && !f.OK {
+			out = append(out, fmt.Sprintf("fts %s: %s", f.Table, f.Message))
+		}
+	}
+	return out
+}
+
+// Doctor runs read-only health checks against the database: SQLite integrity,
+// foreign keys, connection pragmas, FTS index consistency and table sizes.
+func Doctor(ctx context.Context, db *sql.DB, opts DoctorOptions) (*DoctorReport, error) {
+	// Pragmas are per-connection, so pin one for the whole run.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	report := &DoctorReport{}
+
+	check := "integrity_check"
+	if opts.Quick {
+		check = "quick_check"
+	}
+	if report.Integrity, err = queryStrings(ctx, conn, "PRAGMA "+check); err != nil {
+		return nil, fmt.Errorf("%s: %w", check, err)
+	}
+
+	if report.ForeignKeys, err = foreignKeyCheck(ctx, conn); err != nil {
+		return nil, fmt.Errorf("foreign_key_check: %w", err)
+	}
+
+	var file string
+	if err := conn.QueryRowContext(ctx, `SELECT file FROM pragma_database_list WHERE name = 'main'`).Scan(&file); err != nil {
+		return nil, fmt.Errorf("read database file: %w", err)
+	}
+	for _, p := range mergePragmas(opts.Pragmas) {
+		if strings.EqualFold(p.Name, "journal_mode") && (file == "" || opts.ReadOnly) {
+			continue
+		}
+		var actual string
+		err := conn.QueryRowContext(ctx, "PRAGMA "+p.Name).Scan(&actual)
+		if errors.Is(err, sql.ErrNoRows) {
+			// A pragma that only acts, such as optimize, has nothing to check.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read pragma %s: %w", p.Name, err)
+		}
+		expected := pragmaReadback(p.Name, p.Value)
+		report.Pragmas = append(report.Pragmas, PragmaCheck{
+			Name:     p.Name,
+			Expected: expected,
+			Actual:   actual,
+			OK:       strings.EqualFold(actual, expected),
+		})
+	}
+
+	for _, table := range FTSTables {
+		fc, err := checkFTS(ctx, conn, table)
+		if err != nil {
+			return nil, err
+		}
+		report.FTS = append(report.FTS, fc)
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  *int64
+	}{
+		{"page_size", &report.PageSize},
+		{"page_count", &report.PageCount},
+		{"freelist_count", &report.FreePages},
+	} {
+		if err := conn.QueryRowContext(ctx, "PRAGMA "+p.name).Scan(p.dst); err != nil {
+			return nil, fmt.Errorf("read pragma %s: %w", p.name, err)
+		}
+	}
+
+	if !opts.SkipSizes {
+		if report.Tables, err = tableStats(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// MaintainOptions controls which maintenance steps Maintain runs.
+type MaintainOptions struct {
+	// RebuildFTS rebuilds FTS indexes from their content instead of merging segments.
+	RebuildFTS bool
+	// Vacuum rewrites the database file to reclaim free pages.
+	Vacuum bool
+}
+
+// MaintainReport summarises the work done by Maintain.
+type MaintainReport struct {
+	Steps          []string
+	CheckpointLog  int64
+	Checkpointed   int64
+	CheckpointBusy bool
+}
+
+// Maintain runs ANALYZE, PRAGMA optimize, FTS optimize (or rebuild), a WAL
+// checkpoint and, optionally, VACUUM.
+func Maintain(ctx context.Context, db *sql.DB, opts MaintainOptions) (*MaintainReport, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	report := &MaintainReport{}
+	exec := func(step, stmt string) error {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%s: %w", step, err)
+		}
+		report.Steps = append(report.Steps, step)
+		return nil
+	}
+
+	if err := exec("analyze", "ANALYZE"); err != nil {
+		return nil, err
+	}
+	if err := exec("optimize", "PRAGMA optimize"); err != nil {
+		return nil, err
+	}
+
+	command := "optimize"
+	if opts.RebuildFTS {
+		command = "rebuild"
+	}
+	for _, table := range FTSTables {
+		ok, err := tableExists(ctx, conn, table)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		stmt := fmt.Sprintf("INSERT INTO %s(%s) VALUES('%s')", table, table, command)
+		if err := exec(table+" "+command, stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Vacuum {
+		if err := exec("vacuum", "VACUUM"); err != nil {
+			return nil, err
+		}
+	}
+
+	var busy int
+	if err := conn.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &report.CheckpointLog, &report.Checkpointed); err != nil {
+		return nil, fmt.Errorf("wal checkpoint: %w", err)
+	}
+	report.CheckpointBusy = busy != 0
+	report.Steps = append(report.Steps, "wal checkpoint")
+
+	return report, nil
+}
+
+func queryStrings(ctx context.Context, conn *sql.Conn, query string) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func foreignKeyCheck(ctx context.Context, conn *sql.Conn) ([]ForeignKeyViolation, error) {
+	rows, err := conn.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ForeignKeyViolation
+	for rows.Next() {
+		var v ForeignKeyViolation
+		var rowID sql.NullInt64
+		if err := rows.Scan(&v.Table, &rowID, &v.Parent, &v.FKID); err != nil {
+			return nil, err
+		}
+		v.RowID = rowID.Int64
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+func checkFTS(ctx context.Context, conn *sql.Conn, table string) (FTSCheck, error) {
+	fc := FTSCheck{Table: table}
+	ok, err := tableExists(ctx, conn, table)
+	if err != nil || !ok {
+		return fc, err
+	}
+	fc.Exists = true
+
+	// rank=1 also verifies external-content indexes against their content table.
+	stmt := fmt.Sprintf("INSERT INTO %s(%s, rank) VALUES('integrity-check', 1)", table, table)
+	if _, err := conn.ExecContext(ctx, stmt); err != nil {
+		fc.Message = err.Error()
+	} else {
+		fc.OK = true
+	}
+
+	if err := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&fc.Rows); err != nil {
+		return fc, fmt.Errorf("count %s: %w", table, err)
+	}
+	return fc, nil
+}
+
+func tableExists(ctx context.Context, conn *sql.Conn, name string) (bool, error) {
+	var n int
+	err := conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&n)
+	return n > 0, err
+}
+
+func tableStats(ctx context.Context, conn *sql.Conn) ([]TableStats, error) {
+	names, err := queryStrings(ctx, conn, `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+
+	sizes := make(map[string]int64)
+	// dbstat is optional; a build without it still gets row counts.
+	if rows, err := conn.QueryContext(ctx, `SELECT name, SUM(pgsize) FROM dbstat GROUP BY name`); err == nil {
+		for rows.Next() {
+			var name string
+			var size int64
+			if err := rows.Scan(&name, &size); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			sizes[name] = size
+		}
+		rows.Close()
+	}
+
+	stats := make([]TableStats, 0, len(names))
+	for _, name := range names {
+		ts := TableStats{Name: name, Bytes: sizes[name]}
+		q := fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, strings.ReplaceAll(name, `"`, `""`))
+		if err := conn.QueryRowContext(ctx, q).Scan(&ts.Rows); err != nil {
+			return nil, fmt.Errorf("count %s: %w", name, err)
+		}
+		stats = append(stats, ts)
+	}
+	return stats, nil
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestDoctorAndMaintain(t *testing.T) {
+	ctx := context.Background()
+	handle, err := Open(t.TempDir() + "/doctor.db")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer handle.Close()
+
+	if _, err := handle.Exec(`INSERT INTO sessions(id, agent, path, last_user) VALUES('s1', 'claude', '/tmp/s1', 'hello world')`); err != nil {
+		t.Fatalf("insert session: %v", err)
+	}
+
+	report, err := Doctor(ctx, handle, DoctorOptions{})
+	if err != nil {
+		t.Fatalf("Doctor: %v", err)
+	}
+	if !report.Healthy() {
+		t.Fatalf("expected healthy database, got problems: %v", report.Problems())
+	}
+	if len(report.FTS) != len(FTSTables) {
+		t.Fatalf("FTS checks = %d, want %d", len(report.FTS), len(FTSTables))
+	}
+
+	var sessions *TableStats
+	for i := range report.Tables {
+		if report.Tables[i].Name == "sessions" {
+			sessions = &report.Tables[i]
+		}
+	}
+	if sessions == nil || sessions.Rows != 1 {
+		t.Fatalf("sessions stats = %+v, want 1 row", sessions)
+	}
+
+	// Corrupt the external-content index and confirm Doctor notices.
+	if _, err := handle.Exec(`INSERT INTO sessions_fts(rowid, id, last_user) VALUES(999, 'ghost', 'ghost')`); err != nil {
+		t.Fatalf("corrupt fts: %v", err)
+	}
+	report, err = Doctor(ctx, handle, DoctorOptions{Quick: true, SkipSizes: true})
+	if err != nil {
+		t.Fatalf("Doctor: %v", err)
+	}
+	if report.Healthy() {
+		t.Fatalf("expected FTS problem to be reported")
+	}
+
+	mr, err := Maintain(ctx, handle, MaintainOptions{RebuildFTS: true, Vacuum: true})
+	if err != nil {
+		t.Fatalf("Maintain: %v", err)
+	}
+	if len(mr.Steps) == 0 {
+		t.Fatalf("Maintain reported no steps")
+	}
+
+	report, err = Doctor(ctx, handle, DoctorOptions{Quick: true, SkipSizes: true})
+	if err != nil {
+		t.Fatalf("Doctor: %v", err)
+	}
+	if !report.Healthy() {
+		t.Fatalf("expected rebuild to repair FTS, got problems: %v", report.Problems())
+	}
+
+	// Pragmas are checked against what the handle was opened with.
+	opts := Options{Pragmas: []Pragma{{"busy_timeout", "1000"}, {"synchronous", "FULL"}}}
+	custom, err := OpenWithOptions(t.TempDir()+"/custom.db", opts)
+	if err != nil {
+		t.Fatalf("OpenWithOptions: %v", err)
+	}
+	defer custom.Close()
+	memory, err := OpenWithOptions(":memory:", Options{})
+	if err != nil {
+		t.Fatalf("OpenWithOptions memory: %v", err)
+	}
+	defer memory.Close()
+	for name, c := range map[string]struct {
+		handle  *sql.DB
+		pragmas []Pragma
+	}{
+		"custom": {custom, opts.Pragmas},
+		"memory": {memory, nil},
+	} {
+		report, err := Doctor(ctx, c.handle, DoctorOptions{Quick: true, SkipSizes: true, Pragmas: c.pragmas})
+		if err != nil {
+			t.Fatalf("Doctor %s: %v", name, err)
+		}
+		if !report.Healthy() {
+			t.Fatalf("Doctor %s: %v", name, report.Problems())
+		}
+	}
+	report, err = Doctor(ctx, custom, DoctorOptions{Quick: true, SkipSizes: true})
+	if err != nil || report.Healthy() {
+		t.Fatalf("Doctor without the overrides = %v, %v", report.Problems(), err)
+	}
+}
@@ -0,0 +1,510 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package db
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/yourorg/arc-sdk/db/migrations"
+)
+
+// ExportFormat identifies arc export archives.
+const ExportFormat = "arc-export"
+
+// ExportVersion is the archive format version written by Export.
+const ExportVersion = 1
+
+// ConflictPolicy decides what Import does when a row already exists.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing row.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing row with the archived one.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictNewest keeps whichever row has the later timestamp. Tables
+	// without a timestamp column keep the existing row.
+	ConflictNewest ConflictPolicy = "newest"
+)
+
+// exportHeader is the first line of an archive.
+type exportHeader struct {
+	Format        string   `json:"format"`
+	Version       int      `json:"version"`
+	SchemaVersion int      `json:"schema_version"`
+	ExportedAt    int64    `json:"exported_at"`
+	Tables        []string `json:"tables"`
+}
+
+// exportRecord is every following line: a table header carrying Columns,
+// then one line per row carrying Values in the same order.
+type exportRecord struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns,omitempty"`
+	Values  []any    `json:"values,omitempty"`
+}
+
+// ExportOptions controls Export.
+type ExportOptions struct {
+	// Tables limits the export to the named tables (default: all user tables).
+	Tables []string
+}
+
+// ExportStats reports rows written per table.
+type ExportStats struct {
+	Rows map[string]int
+}
+
+// Export writes every user table (excluding FTS indexes, their shadow tables
+// and migration bookkeeping) to w as a versioned NDJSON archive.
+func Export(ctx context.Context, q Querier, w io.Writer, opts ExportOptions) (*ExportStats, error) {
+	metas, err := userTables(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	metas = filterTables(metas, opts.Tables)
+
+	version, err := schemaVersion(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	header := exportHeader{
+		Format:        ExportFormat,
+		Version:       ExportVersion,
+		SchemaVersion: version,
+		ExportedAt:    time.Now().Unix(),
+	}
+	for _, m := range metas {
+		header.Tables = append(header.Tables, m.name)
+	}
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+
+	stats := &ExportStats{Rows: make(map[string]int)}
+	for _, m := range metas {
+		n, err := exportTable(ctx, q, enc, m)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", m.name, err)
+		}
+		stats.Rows[m.name] = n
+	}
+	return stats, bw.Flush()
+}
+
+func exportTable(ctx context.Context, q Querier, enc *json.Encoder, m tableMeta) (int, error) {
+	if err := enc.Encode(exportRecord{Table: m.name, Columns: m.columns}); err != nil {
+		return 0, err
+	}
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`SELECT %s FROM %s ORDER BY %s`,
+		quoteIdents(m.columns), quoteIdent(m.name), quoteIdents(m.key)))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		values := make([]any, len(m.columns))
+		ptrs := make([]any, len(values))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return n, err
+		}
+		for i, v := range values {
+			values[i] = encodeValue(v)
+		}
+		if err := enc.Encode(exportRecord{Table: m.name, Values: values}); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// ImportOptions controls Import.
+type ImportOptions struct {
+	// Conflict decides what happens to rows that already exist (default skip).
+	Conflict ConflictPolicy
+	// Tables limits the import to the named tables (default: all in the archive).
+	Tables []string
+}
+
+// ImportStats reports what Import did per table.
+type ImportStats struct {
+	Inserted map[string]int
+	Updated  map[string]int
+	Skipped  map[string]int
+	// MissingTables lists archived tables that do not exist in the target.
+	MissingTables []string
+}
+
+// Import reads an archive written by Export into d. The target is migrated
+// first, rows are upserted according to opts.Conflict inside one transaction,
+// and FTS indexes are rebuilt afterwards.
+func Import(ctx context.Context, d *sql.DB, r io.Reader, opts ImportOptions) (*ImportStats, error) {
+	switch opts.Conflict {
+	case "":
+		opts.Conflict = ConflictSkip
+	case ConflictSkip, ConflictOverwrite, ConflictNewest:
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q", opts.Conflict)
+	}
+
+	if err := migrations.RunMigrations(d); err != nil {
+		return nil, fmt.Errorf("migrate target: %w", err)
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	dec.UseNumber()
+
+	var header exportHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("read archive header: %w", err)
+	}
+	if header.Format != ExportFormat {
+		return nil, fmt.Errorf("not an arc export archive (format %q)", header.Format)
+	}
+	if header.Version > ExportVersion {
+		return nil, fmt.Errorf("archive version %d is newer than supported version %d", header.Version, ExportVersion)
+	}
+
+	stats := &ImportStats{
+		Inserted: make(map[string]int),
+		Updated:  make(map[string]int),
+		Skipped:  make(map[string]int),
+	}
+	only := make(map[string]bool)
+	for _, t := range opts.Tables {
+		only[t] = true
+	}
+
+	err := WithTx(ctx, d, func(tx *sql.Tx) error {
+		// Rows arrive parent-first, but cycles and filtered tables may still
+		// reference rows that appear later.
+		if _, err := tx.ExecContext(ctx, "PRAGMA defer_foreign_keys = ON"); err != nil {
+			return err
+		}
+		version, err := schemaVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if header.SchemaVersion > version {
+			return fmt.Errorf("archive schema version %d is newer than target version %d", header.SchemaVersion, version)
+		}
+
+		metas, err := userTables(ctx, tx)
+		if err != nil {
+			return err
+		}
+		byName := make(map[string]tableMeta, len(metas))
+		for _, m := range metas {
+			byName[m.name] = m
+		}
+
+		w := newRowWriter(tx)
+		var current *tableMeta
+		var columns []string
+		for {
+			var rec exportRecord
+			if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return fmt.Errorf("read archive: %w", err)
+			}
+
+			if rec.Columns != nil {
+				current, columns = nil, rec.Columns
+				if len(only) > 0 && !only[rec.Table] {
+					continue
+				}
+				m, ok := byName[rec.Table]
+				if !ok {
+					stats.MissingTables = append(stats.MissingTables, rec.Table)
+					continue
+				}
+				current = &m
+				continue
+			}
+			if current == nil || rec.Table != current.name {
+				continue
+			}
+			if len(rec.Values) != len(columns) {
+				return fmt.Errorf("%s: row has %d values for %d columns", rec.Table, len(rec.Values), len(columns))
+			}
+
+			row := make(map[string]any, len(columns))
+			for i, c := range columns {
+				if !current.hasColumn(c) {
+					continue
+				}
+				v, err := decodeValue(rec.Values[i])
+				if err != nil {
+					return fmt.Errorf("%s.%s: %w", rec.Table, c, err)
+				}
+				row[c] = v
+			}
+
+			action, err := w.apply(ctx, *current, row, opts.Conflict)
+			if err != nil {
+				return fmt.Errorf("import %s: %w", rec.Table, err)
+			}
+			switch action {
+			case rowInserted:
+				stats.Inserted[rec.Table]++
+			case rowUpdated:
+				stats.Updated[rec.Table]++
+			default:
+				stats.Skipped[rec.Table]++
+			}
+		}
+		return rebuildFTS(ctx, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+type rowAction int
+
+const (
+	rowSkipped rowAction = iota
+	rowInserted
+	rowUpdated
+)
+
+// rowWriter upserts rows table by table, remembering how natural-key ids were
+// reassigned so later rows referencing them can be rewritten.
+type rowWriter struct {
+	q     Querier
+	remap map[string]map[string]any
+}
+
+func newRowWriter(q Querier) *rowWriter {
+	return &rowWriter{q: q, remap: make(map[string]map[string]any)}
+}
+
+func (w *rowWriter) apply(ctx context.Context, m tableMeta, row map[string]any, policy ConflictPolicy) (rowAction, error) {
+	w.remapForeignKeys(m, row)
+	return w.upsert(ctx, m, row, policy)
+}
+
+// remapForeignKeys rewrites references to rows whose id was reassigned.
+func (w *rowWriter) remapForeignKeys(m tableMeta, row map[string]any) {
+	for _, fk := range m.fks {
+		ids, ok := w.remap[fk.parent]
+		if !ok {
+			continue
+		}
+		if v, ok := row[fk.column]; ok && v != nil {
+			if mapped, ok := ids[idKey(v)]; ok {
+				row[fk.column] = mapped
+			}
+		}
+	}
+}
+
+// upsert writes an already remapped row according to policy.
+func (w *rowWriter) upsert(ctx context.Context, m tableMeta, row map[string]any, policy ConflictPolicy) (rowAction, error) {
+	var oldID any
+	if m.autoID != "" {
+		oldID = row[m.autoID]
+	}
+
+	existingID, existingTS, found, err := w.lookup(ctx, m, row)
+	if err != nil {
+		return rowSkipped, err
+	}
+
+	if !found {
+		newID, inserted, err := w.insert(ctx, m, row)
+		if err != nil || !inserted {
+			return rowSkipped, err
+		}
+		w.remember(m, oldID, newID)
+		return rowInserted, nil
+	}
+
+	w.remember(m, oldID, existingID)
+	switch policy {
+	case ConflictOverwrite:
+	case ConflictNewest:
+		if len(m.timestamps) == 0 || compareValues(maxValue(row, m.timestamps), existingTS) <= 0 {
+			return rowSkipped, nil
+		}
+	default:
+		return rowSkipped, nil
+	}
+	if err := w.update(ctx, m, row); err != nil {
+		return rowSkipped, err
+	}
+	return rowUpdated, nil
+}
+
+func (w *rowWriter) remember(m tableMeta, oldID, newID any) {
+	if m.autoID == "" || oldID == nil || newID == nil {
+		return
+	}
+	if w.remap[m.name] == nil {
+		w.remap[m.name] = make(map[string]any)
+	}
+	w.remap[m.name][idKey(oldID)] = newID
+}
+
+func keyClause(m tableMeta, row map[string]any) (string, []any) {
+	parts := make([]string, len(m.key))
+	args := make([]any, len(m.key))
+	for i, k := range m.key {
+		parts[i] = quoteIdent(k) + " IS ?"
+		args[i] = row[k]
+	}
+	return strings.Join(parts, " AND "), args
+}
+
+// lookup finds an existing row with the same key, returning its id (for
+// natural-key tables) and its newest timestamp.
+func (w *rowWriter) lookup(ctx context.Context, m tableMeta, row map[string]any) (any, any, bool, error) {
+	where, args := keyClause(m, row)
+	cols := []string{"1"}
+	if m.autoID != "" {
+		cols = append(cols, quoteIdent(m.autoID))
+	}
+	for _, t := range m.timestamps {
+		cols = append(cols, quoteIdent(t))
+	}
+	dest := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range dest {
+		ptrs[i] = &dest[i]
+	}
+	err := w.q.QueryRowContext(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE %s LIMIT 1`,
+		strings.Join(cols, ", "), quoteIdent(m.name), where), args...).Scan(ptrs...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	var id any
+	tsStart := 1
+	if m.autoID != "" {
+		id = dest[1]
+		tsStart = 2
+	}
+	existing := make(map[string]any, len(m.timestamps))
+	for i, t := range m.timestamps {
+		existing[t] = dest[tsStart+i]
+	}
+	return id, maxValue(existing, m.timestamps), true, nil
+}
+
+func (w *rowWriter) insert(ctx context.Context, m tableMeta, row map[string]any) (any, bool, error) {
+	var cols []string
+	var args []any
+	for _, c := range m.columns {
+		v, ok := row[c]
+		if !ok || c == m.autoID {
+			continue
+		}
+		cols = append(cols, c)
+		args = append(args, v)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(cols)), ",")
+	// Other unique constraints (e.g. a URL already indexed under another
+	// name) make the row a conflict rather than an error.
+	res, err := w.q.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s(%s) VALUES(%s) ON CONFLICT DO NOTHING`,
+		quoteIdent(m.name), quoteIdents(cols), placeholders), args...)
+	if err != nil {
+		return nil, false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, false, nil
+	}
+	if m.autoID == "" {
+		return nil, true, nil
+	}
+	id, err := res.LastInsertId()
+	return id, true, err
+}
+
+func (w *rowWriter) update(ctx context.Context, m tableMeta, row map[string]any) error {
+	keys := make(map[string]bool, len(m.key))
+	for _, k := range m.key {
+		keys[k] = true
+	}
+	var sets []string
+	var args []any
+	for _, c := range m.columns {
+		v, ok := row[c]
+		if !ok || keys[c] || c == m.autoID {
+			continue
+		}
+		sets = append(sets, quoteIdent(c)+" = ?")
+		args = append(args, v)
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	where, keyArgs := keyClause(m, row)
+	_, err := w.q.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET %s WHERE %s`,
+		quoteIdent(m.name), strings.Join(sets, ", "), where), append(args, keyArgs...)...)
+	return err
+}
+
+// rebuildFTS rebuilds every FTS index present in the schema.
+func rebuildFTS(ctx context.Context, q Querier) error {
+	for _, table := range FTSTables {
+		var n int
+		if err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+		if _, err := q.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s(%s) VALUES('rebuild')", table, table)); err != nil {
+			return fmt.Errorf("rebuild %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func schemaVersion(ctx context.Context, q Querier) (int, error) {
+	var v sql.NullInt64
+	if err := q.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&v); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return int(v.Int64), nil
+}
+
+func filterTables(metas []tableMeta, names []string) []tableMeta {
+	if len(names) == 0 {
+		return metas
+	}
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+	var out []tableMeta
+	for _, m := range metas {
+		if want[m.name] {
+			out = append(out, m)
+		}
+	}
+	return out
+}
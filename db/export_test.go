@@ -0,0 +1,113 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func openExportDB(t *testing.T, name string) *sql.DB {
+	t.Helper()
+	handle, err := Open(t.TempDir() + "/" + name)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { handle.Close() })
+	return handle
+}
+
+func mustExec(t *testing.T, handle *sql.DB, stmts ...string) {
+	t.Helper()
+	for _, stmt := range stmts {
+		if _, err := handle.Exec(stmt); err != nil {
+			t.Fatalf("exec %q: %v", stmt, err)
+		}
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := openExportDB(t, "src.db")
+	mustExec(t, src,
+		`INSERT INTO items(id, slug, title, type, updated_at) VALUES('i1', 'one', 'Remote title', 'note', '2025-02-01')`,
+		`INSERT INTO tags(id, name) VALUES(7, 'go')`,
+		`INSERT INTO item_tags(item_id, tag_id) VALUES('i1', 7)`,
+		`INSERT INTO sessions(id, agent, path, mod_ts, last_user) VALUES('s1', 'claude', '/tmp/s1', 100, 'hello world')`,
+		`INSERT INTO external_repos(name, url, added_at) VALUES('llm', 'https://github.com/simonw/llm', 1)`,
+	)
+
+	var buf bytes.Buffer
+	es, err := Export(ctx, src, &buf, ExportOptions{})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if es.Rows["items"] != 1 || es.Rows["item_tags"] != 1 {
+		t.Fatalf("export rows = %v", es.Rows)
+	}
+	archive := buf.Bytes()
+
+	dst := openExportDB(t, "dst.db")
+	mustExec(t, dst,
+		`INSERT INTO items(id, slug, title, type, updated_at) VALUES('i1', 'one', 'Local title', 'note', '2025-01-01')`,
+		`INSERT INTO tags(id, name) VALUES(3, 'go')`,
+	)
+
+	is, err := Import(ctx, dst, bytes.NewReader(archive), ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if is.Skipped["items"] != 1 || is.Inserted["sessions"] != 1 {
+		t.Fatalf("import stats = %+v", is)
+	}
+
+	// The tag already existed under another id; the join row must follow it.
+	var tagID int
+	if err := dst.QueryRow(`SELECT tag_id FROM item_tags WHERE item_id = 'i1'`).Scan(&tagID); err != nil {
+		t.Fatalf("item_tags: %v", err)
+	}
+	if tagID != 3 {
+		t.Fatalf("tag_id = %d, want remapped 3", tagID)
+	}
+
+	var hits int
+	if err := dst.QueryRow(`SELECT COUNT(*) FROM sessions_fts WHERE sessions_fts MATCH 'hello'`).Scan(&hits); err != nil {
+		t.Fatalf("fts: %v", err)
+	}
+	if hits != 1 {
+		t.Fatalf("fts hits = %d, want 1", hits)
+	}
+
+	var title string
+	if err := dst.QueryRow(`SELECT title FROM items WHERE id = 'i1'`).Scan(&title); err != nil {
+		t.Fatalf("title: %v", err)
+	}
+	if title != "Local title" {
+		t.Fatalf("skip policy replaced row: %q", title)
+	}
+
+	is, err = Import(ctx, dst, bytes.NewReader(archive), ImportOptions{Conflict: ConflictNewest})
+	if err != nil {
+		t.Fatalf("Import newest: %v", err)
+	}
+	if is.Updated["items"] != 1 || is.Inserted["item_tags"] != 0 {
+		t.Fatalf("import stats = %+v", is)
+	}
+	if err := dst.QueryRow(`SELECT title FROM items WHERE id = 'i1'`).Scan(&title); err != nil {
+		t.Fatalf("title: %v", err)
+	}
+	if title != "Remote title" {
+		t.Fatalf("newest policy kept stale row: %q", title)
+	}
+
+	report, err := Doctor(ctx, dst, DoctorOptions{Quick: true, SkipSizes: true})
+	if err != nil {
+		t.Fatalf("Doctor: %v", err)
+	}
+	if !report.Healthy() {
+		t.Fatalf("imported database unhealthy: %v", report.Problems())
+	}
+}
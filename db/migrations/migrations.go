@@ -8,7 +8,9 @@ import (
 	"embed"
 	"fmt"
 	"io/fs"
+	"net/url"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -23,8 +25,42 @@ type mig struct {
 	path    string
 }
 
+// KVStoreVersion is the migration that creates the kv_store table. It
+// depends on no other migration, so it can be applied on its own.
+const KVStoreVersion = 14
+
+// KVVersionSeqVersion is the migration that adds kv_store's version sequence.
+// It depends only on KVStoreVersion.
+const KVVersionSeqVersion = 18
+
+// canonicalURLsVersion is the migration that adds external_repos.canonical_url.
+const canonicalURLsVersion = 17
+
+// goSteps run inside a migration's transaction before its SQL, for changes
+// plain SQL cannot express conditionally.
+var goSteps = map[int]func(tx *sql.Tx) error{
+	KVStoreVersion:       prepareKVStore,
+	canonicalURLsVersion: addCanonicalURLs,
+}
+
 // RunMigrations applies any pending embedded SQL migrations.
 func RunMigrations(db *sql.DB) error {
+	return run(db, func(int) bool { return true })
+}
+
+// RunVersions applies the given embedded migrations if they are pending,
+// recording them in schema_migrations as RunMigrations does. It suits
+// databases that hold only part of the arc schema; a later RunMigrations
+// applies the rest.
+func RunVersions(db *sql.DB, versions ...int) error {
+	want := make(map[int]bool, len(versions))
+	for _, v := range versions {
+		want[v] = true
+	}
+	return run(db, func(v int) bool { return want[v] })
+}
+
+func run(db *sql.DB, want func(version int) bool) error {
 	if err := ensureSchemaTable(db); err != nil {
 		return err
 	}
@@ -40,8 +76,8 @@ func RunMigrations(db *sql.DB) error {
 	}
 
 	for _, m := range migs {
-		if _, ok := applied[m.version]; ok {
-			continue // already applied
+		if _, ok := applied[m.version]; ok || !want(m.version) {
+			continue // already applied or not requested
 		}
 		if err := applyOne(db, m); err != nil {
 			return fmt.Errorf("apply migration %03d_%s: %w", m.version, m.name, err)
@@ -112,6 +148,12 @@ func applyOne(db *sql.DB, m mig) error {
 	if err != nil {
 		return err
 	}
+	if step, ok := goSteps[m.version]; ok {
+		if err := step(tx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
 	if _, err := tx.Exec(string(sqlBytes)); err != nil {
 		_ = tx.Rollback()
 		return err
@@ -160,3 +202,96 @@ func Applied(db *sql.DB) (map[int]string, error) {
 	}
 	return m, rows.Err()
 }
+
+// prepareKVStore adds the columns a kv_store table created by an older
+// store.OpenSQLiteStore may lack, so 014_kv_store.sql can index them.
+func prepareKVStore(tx *sql.Tx) error {
+	var tables int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'kv_store'`).Scan(&tables); err != nil {
+		return err
+	}
+	if tables == 0 {
+		return nil // the SQL creates the table
+	}
+	for _, col := range []struct{ name, def string }{
+		{"expires_at", "INTEGER"},
+		// Existing rows count as written once.
+		{"version", "INTEGER NOT NULL DEFAULT 1"},
+	} {
+		var n int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('kv_store') WHERE name = ?`, col.name).Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := tx.Exec(`ALTER TABLE kv_store ADD COLUMN ` + col.name + ` ` + col.def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addCanonicalURLs adds external_repos.canonical_url and fills it for
+// existing rows, so 017_canonical_urls.sql can index it.
+func addCanonicalURLs(tx *sql.Tx) error {
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('external_repos') WHERE name = 'canonical_url'`).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		if _, err := tx.Exec(`ALTER TABLE external_repos ADD COLUMN canonical_url TEXT`); err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.Query(`SELECT name, url FROM external_repos`)
+	if err != nil {
+		return err
+	}
+	canonical := make(map[string]string)
+	for rows.Next() {
+		var name, url string
+		if err := rows.Scan(&name, &url); err != nil {
+			rows.Close()
+			return err
+		}
+		canonical[name] = canonicalURL017(url)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for name, url := range canonical {
+		if _, err := tx.Exec(`UPDATE external_repos SET canonical_url = ? WHERE name = ?`, url, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scpURL017 matches scp-style [user@]host:path remotes.
+var scpURL017 = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// canonicalURL017 is git.CanonicalURL as it was when migration 17 was
+// written. It is a copy so the backfill never changes with the git package;
+// store code keeps later rows up to date.
+func canonicalURL017(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	var host, path string
+	if m := scpURL017.FindStringSubmatch(rawURL); m != nil && !strings.Contains(rawURL, "://") {
+		host, path = m[1], m[2]
+	} else if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+	} else {
+		return rawURL
+	}
+
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	path = strings.TrimRight(path, "/")
+	if path == "" {
+		return "https://" + host
+	}
+	return "https://" + host + "/" + path
+}
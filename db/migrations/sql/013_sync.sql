@@ -0,0 +1,32 @@
+-- Bookkeeping for two-way sync between machines (see db.Sync).
+-- Row-level change triggers are installed at runtime by db.EnableSync so that
+-- tables added by later migrations are covered too.
+
+-- machine_id and the local export cursor; 'applying' is present only while
+-- remote changes are merged, which silences the change triggers.
+CREATE TABLE IF NOT EXISTS sync_state (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+-- Latest known version of every synced row. ts/machine order versions for
+-- last-writer-wins; deleted rows are kept as tombstones. seq orders local
+-- changes for export and is never reused.
+CREATE TABLE IF NOT EXISTS sync_rows (
+    table_name TEXT NOT NULL,
+    row_key TEXT NOT NULL,
+    ts INTEGER NOT NULL,
+    machine TEXT NOT NULL,
+    deleted INTEGER NOT NULL DEFAULT 0,
+    seq INTEGER,
+    PRIMARY KEY (table_name, row_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_rows_seq ON sync_rows(seq);
+
+-- How far each peer's change log has been merged.
+CREATE TABLE IF NOT EXISTS sync_peers (
+    machine TEXT PRIMARY KEY,
+    last_seq INTEGER NOT NULL DEFAULT 0,
+    synced_at INTEGER
+);
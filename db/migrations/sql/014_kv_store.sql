@@ -0,0 +1,14 @@
+-- Generic key-value store backing store.KVStore. Before this migration the
+-- store created the table itself; columns such an older table lacks are
+-- added by the migration's Go step (see prepareKVStore) before this runs.
+CREATE TABLE IF NOT EXISTS kv_store (
+    key TEXT PRIMARY KEY,
+    value TEXT,
+    updated_at INTEGER NOT NULL,
+    -- Unix nanoseconds; NULL for no expiry.
+    expires_at INTEGER,
+    -- Starts at 1 and increases with every write (see CompareAndSet).
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_kv_store_expires_at ON kv_store(expires_at) WHERE expires_at IS NOT NULL;
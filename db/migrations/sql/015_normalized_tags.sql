@@ -0,0 +1,141 @@
+-- Repo and session tags move into join tables on the shared tags table.
+-- external_repos.tags (JSON array) and sessions.tags (comma-separated) stay
+-- as mirrors kept current by triggers, so existing readers and
+-- external_repos_fts keep working. Tag names may be paths such as "lang/go".
+PRAGMA foreign_keys = ON;
+
+CREATE TABLE IF NOT EXISTS repo_tags (
+    repo_name TEXT NOT NULL,
+    tag_id INTEGER NOT NULL,
+    created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (repo_name, tag_id),
+    FOREIGN KEY (repo_name) REFERENCES external_repos(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_repo_tags_tag ON repo_tags(tag_id);
+
+CREATE TABLE IF NOT EXISTS session_tags (
+    session_id TEXT NOT NULL,
+    tag_id INTEGER NOT NULL,
+    created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id, tag_id),
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_tags_tag ON session_tags(tag_id);
+
+-- Backfill from the old columns. Repo tags are usually JSON arrays; anything
+-- else, including every session's tags, is split on commas.
+CREATE TEMP TABLE legacy_tags (kind TEXT NOT NULL, id TEXT NOT NULL, pos INTEGER NOT NULL, name TEXT NOT NULL);
+
+INSERT INTO legacy_tags(kind, id, pos, name)
+SELECT 'repo', r.name, j.key, trim(j.value)
+FROM external_repos r, json_each(r.tags) j
+WHERE json_valid(r.tags) AND json_type(r.tags) = 'array' AND j.type = 'text';
+
+WITH RECURSIVE split(kind, id, pos, part, rest) AS (
+    SELECT 'repo', name, 0, NULL, tags || ',' FROM external_repos
+    WHERE tags IS NOT NULL AND NOT (json_valid(tags) AND json_type(tags) = 'array')
+    UNION ALL
+    SELECT 'session', id, 0, NULL, tags || ',' FROM sessions WHERE tags IS NOT NULL
+    UNION ALL
+    SELECT kind, id, pos + 1, trim(substr(rest, 1, instr(rest, ',') - 1)), substr(rest, instr(rest, ',') + 1)
+    FROM split WHERE rest != ''
+)
+INSERT INTO legacy_tags(kind, id, pos, name)
+SELECT kind, id, pos, part FROM split WHERE part IS NOT NULL;
+
+DELETE FROM legacy_tags WHERE name = '';
+
+INSERT OR IGNORE INTO tags(name) SELECT DISTINCT name FROM legacy_tags;
+
+INSERT OR IGNORE INTO repo_tags(repo_name, tag_id)
+SELECT l.id, t.id FROM legacy_tags l JOIN tags t ON t.name = l.name
+WHERE l.kind = 'repo' ORDER BY l.id, l.pos;
+
+INSERT OR IGNORE INTO session_tags(session_id, tag_id)
+SELECT l.id, t.id FROM legacy_tags l JOIN tags t ON t.name = l.name
+WHERE l.kind = 'session' ORDER BY l.id, l.pos;
+
+DROP TABLE legacy_tags;
+
+-- Mirrors list tags in the order they were attached.
+UPDATE external_repos SET tags = (
+    SELECT json_group_array(name) FROM (
+        SELECT t.name FROM repo_tags rt JOIN tags t ON t.id = rt.tag_id
+        WHERE rt.repo_name = external_repos.name ORDER BY rt.rowid
+    )
+) WHERE tags IS NOT NULL;
+
+UPDATE sessions SET tags = COALESCE((
+    SELECT group_concat(name, ',') FROM (
+        SELECT t.name FROM session_tags st JOIN tags t ON t.id = st.tag_id
+        WHERE st.session_id = sessions.id ORDER BY st.rowid
+    )
+), '') WHERE tags IS NOT NULL;
+
+CREATE TRIGGER IF NOT EXISTS repo_tags_mirror_insert AFTER INSERT ON repo_tags BEGIN
+    UPDATE external_repos SET tags = (
+        SELECT json_group_array(name) FROM (
+            SELECT t.name FROM repo_tags rt JOIN tags t ON t.id = rt.tag_id
+            WHERE rt.repo_name = new.repo_name ORDER BY rt.rowid
+        )
+    ) WHERE name = new.repo_name;
+END;
+
+CREATE TRIGGER IF NOT EXISTS repo_tags_mirror_delete AFTER DELETE ON repo_tags BEGIN
+    UPDATE external_repos SET tags = (
+        SELECT json_group_array(name) FROM (
+            SELECT t.name FROM repo_tags rt JOIN tags t ON t.id = rt.tag_id
+            WHERE rt.repo_name = old.repo_name ORDER BY rt.rowid
+        )
+    ) WHERE name = old.repo_name;
+END;
+
+CREATE TRIGGER IF NOT EXISTS session_tags_mirror_insert AFTER INSERT ON session_tags BEGIN
+    UPDATE sessions SET tags = COALESCE((
+        SELECT group_concat(name, ',') FROM (
+            SELECT t.name FROM session_tags st JOIN tags t ON t.id = st.tag_id
+            WHERE st.session_id = new.session_id ORDER BY st.rowid
+        )
+    ), '') WHERE id = new.session_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS session_tags_mirror_delete AFTER DELETE ON session_tags BEGIN
+    UPDATE sessions SET tags = COALESCE((
+        SELECT group_concat(name, ',') FROM (
+            SELECT t.name FROM session_tags st JOIN tags t ON t.id = st.tag_id
+            WHERE st.session_id = old.session_id ORDER BY st.rowid
+        )
+    ), '') WHERE id = old.session_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS tags_mirror_rename AFTER UPDATE OF name ON tags BEGIN
+    UPDATE external_repos SET tags = (
+        SELECT json_group_array(name) FROM (
+            SELECT t.name FROM repo_tags rt JOIN tags t ON t.id = rt.tag_id
+            WHERE rt.repo_name = external_repos.name ORDER BY rt.rowid
+        )
+    ) WHERE name IN (SELECT repo_name FROM repo_tags WHERE tag_id = new.id);
+    UPDATE sessions SET tags = COALESCE((
+        SELECT group_concat(name, ',') FROM (
+            SELECT t.name FROM session_tags st JOIN tags t ON t.id = st.tag_id
+            WHERE st.session_id = sessions.id ORDER BY st.rowid
+        )
+    ), '') WHERE id IN (SELECT session_id FROM session_tags WHERE tag_id = new.id);
+END;
+
+-- The original update trigger issued a plain UPDATE against the
+-- external-content FTS table, which leaves stale terms behind. Replace it
+-- with the delete-then-insert form and rebuild the index once.
+DROP TRIGGER IF EXISTS external_repos_fts_update;
+CREATE TRIGGER external_repos_fts_update AFTER UPDATE ON external_repos BEGIN
+    INSERT INTO external_repos_fts(external_repos_fts, rowid, name, description, topics, tags, notes)
+    VALUES ('delete', old.rowid, old.name, old.description, old.topics, old.tags, old.notes);
+    INSERT INTO external_repos_fts(rowid, name, description, topics, tags, notes)
+    VALUES (new.rowid, new.name, new.description, new.topics, new.tags, new.notes);
+END;
+
+INSERT INTO external_repos_fts(external_repos_fts) VALUES ('rebuild');
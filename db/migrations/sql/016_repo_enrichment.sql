@@ -0,0 +1,14 @@
+-- Forge metadata enrichment bookkeeping, one row per repo (see package enrich).
+PRAGMA foreign_keys = ON;
+
+CREATE TABLE IF NOT EXISTS repo_enrichment (
+    repo_name TEXT PRIMARY KEY,
+    provider TEXT NOT NULL,           -- forge host, e.g. 'github.com'
+    etag TEXT,                        -- validator for conditional requests
+    enriched_at INTEGER,              -- last successful fetch (200 or 304)
+    checked_at INTEGER NOT NULL,      -- last attempt, successful or not
+    error TEXT,                       -- last attempt's error; NULL on success
+    FOREIGN KEY(repo_name) REFERENCES external_repos(name) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_repo_enrichment_enriched_at ON repo_enrichment(enriched_at);
@@ -0,0 +1,16 @@
+-- Canonical repo URLs for duplicate detection, and redirects from the old
+-- locations of moved repos. The external_repos.canonical_url column is added
+-- and backfilled by the migration's Go step (see addCanonicalURLs), since
+-- computing it takes git.CanonicalURL.
+PRAGMA foreign_keys = ON;
+
+CREATE INDEX IF NOT EXISTS idx_external_repos_canonical_url ON external_repos(canonical_url);
+
+CREATE TABLE IF NOT EXISTS repo_redirects (
+    canonical_url TEXT PRIMARY KEY,   -- where the repo used to live
+    repo_name TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY(repo_name) REFERENCES external_repos(name) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_repo_redirects_repo_name ON repo_redirects(repo_name);
@@ -0,0 +1,10 @@
+-- Store-wide sequence for kv_store versions (see store.KVStore.CompareAndSet).
+-- Every write takes the next number, so a key that is deleted and written
+-- again never returns to a version an earlier reader may still hold.
+CREATE TABLE IF NOT EXISTS kv_meta (
+    name TEXT PRIMARY KEY,
+    value INTEGER NOT NULL
+);
+
+INSERT OR IGNORE INTO kv_meta(name, value)
+SELECT 'version_seq', COALESCE(MAX(version), 0) FROM kv_store;
@@ -0,0 +1,59 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Querier is the query surface shared by *sql.DB, *sql.Tx and *sql.Conn, so
+// the same store code can run standalone or inside a caller's transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var (
+	_ Querier = (*sql.DB)(nil)
+	_ Querier = (*sql.Tx)(nil)
+	_ Querier = (*sql.Conn)(nil)
+)
+
+// InTx runs fn atomically on q: in a new transaction when q is a *sql.DB or
+// *sql.Conn, or in a savepoint when q is already a *sql.Tx.
+func InTx(ctx context.Context, q Querier, fn func(*sql.Tx) error) error {
+	switch v := q.(type) {
+	case *sql.Tx:
+		return WithSavepoint(ctx, v, fn)
+	case *sql.DB:
+		return WithTx(ctx, v, fn)
+	case *sql.Conn:
+		return connTx(ctx, v, fn)
+	default:
+		return fmt.Errorf("db: cannot start a transaction on %T", q)
+	}
+}
+
+// connTx runs fn in a transaction on conn, rolling back on error.
+func connTx(ctx context.Context, conn *sql.Conn, fn func(*sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,622 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package db
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourorg/arc-sdk/db/migrations"
+)
+
+// syncNow is the trigger-side clock: Unix time in milliseconds.
+const syncNow = `CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER)`
+
+// SyncOptions controls Sync.
+type SyncOptions struct {
+	// Dir is the shared directory (e.g. a synced folder) holding one change
+	// log directory per machine.
+	Dir string
+	// MachineID names this database in Dir. It is generated and stored on
+	// first use when empty, and must not change afterwards.
+	MachineID string
+}
+
+// SyncReport summarises a Sync run.
+type SyncReport struct {
+	MachineID string
+	// Exported is the number of local row changes written to Dir.
+	Exported int
+	// Applied and Deleted count remote changes merged into the database;
+	// Skipped counts remote changes that lost to a newer local version.
+	Applied int
+	Deleted int
+	Skipped int
+	Peers   []string
+}
+
+// syncChange is one line of a machine's change log. Ref lines carry a parent
+// row referenced by the following change so its local id can be resolved.
+type syncChange struct {
+	Seq     int64    `json:"seq"`
+	Table   string   `json:"table"`
+	Key     []any    `json:"key"`
+	TS      int64    `json:"ts"`
+	Deleted bool     `json:"deleted,omitempty"`
+	Ref     bool     `json:"ref,omitempty"`
+	Columns []string `json:"columns,omitempty"`
+	Values  []any    `json:"values,omitempty"`
+}
+
+// EnableSync installs change-tracking triggers on every user table and
+// returns the database's machine id. Rows that existed before tracking was
+// enabled are recorded with timestamp 0 so any later edit elsewhere wins.
+// It is safe to call repeatedly; Sync calls it on every run.
+func EnableSync(ctx context.Context, d *sql.DB, machineID string) (string, error) {
+	if err := migrations.RunMigrations(d); err != nil {
+		return "", fmt.Errorf("migrate: %w", err)
+	}
+
+	var id string
+	err := WithTx(ctx, d, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `SELECT value FROM sync_state WHERE key = 'machine_id'`).Scan(&id)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			id = machineID
+			if id == "" {
+				buf := make([]byte, 8)
+				if _, err := rand.Read(buf); err != nil {
+					return err
+				}
+				id = hex.EncodeToString(buf)
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO sync_state(key, value) VALUES('machine_id', ?)`, id); err != nil {
+				return err
+			}
+		case err != nil:
+			return fmt.Errorf("read machine id: %w", err)
+		case machineID != "" && machineID != id:
+			return fmt.Errorf("database is already synced as machine %q, not %q", id, machineID)
+		}
+
+		metas, err := userTables(ctx, tx)
+		if err != nil {
+			return err
+		}
+		for _, m := range metas {
+			if err := installSyncTriggers(ctx, tx, m, id); err != nil {
+				return fmt.Errorf("sync triggers %s: %w", m.name, err)
+			}
+		}
+		return nil
+	})
+	return id, err
+}
+
+// Sync writes local changes made since the last run to Dir/<machine id>/ and
+// merges every other machine's log into the database. Conflicts resolve per
+// row by last writer wins on (timestamp, machine id), so all machines converge
+// on the same state regardless of the order they sync in.
+func Sync(ctx context.Context, d *sql.DB, opts SyncOptions) (*SyncReport, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("sync directory is required")
+	}
+	id, err := EnableSync(ctx, d, opts.MachineID)
+	if err != nil {
+		return nil, err
+	}
+	report := &SyncReport{MachineID: id}
+	if report.Exported, err = pushChanges(ctx, d, opts.Dir, id); err != nil {
+		return nil, fmt.Errorf("push: %w", err)
+	}
+	if err := pullChanges(ctx, d, opts.Dir, id, report); err != nil {
+		return nil, fmt.Errorf("pull: %w", err)
+	}
+	return report, nil
+}
+
+func syncTriggerName(table, event string) string {
+	return quoteIdent("arc_sync_" + table + "_" + event)
+}
+
+func syncKeyExpr(prefix string, m tableMeta) string {
+	parts := make([]string, len(m.key))
+	for i, k := range m.key {
+		parts[i] = prefix + "." + quoteIdent(k)
+	}
+	return "json_array(" + strings.Join(parts, ", ") + ")"
+}
+
+// syncUpsert records a local change to a row; the timestamp never goes
+// backwards for a row, even if the wall clock does.
+func syncUpsert(table, keyExpr string, deleted int, where string) string {
+	return fmt.Sprintf(`
+	INSERT INTO sync_rows(table_name, row_key, ts, machine, deleted, seq)
+	SELECT '%s', %s, %s, (SELECT value FROM sync_state WHERE key = 'machine_id'), %d,
+		(SELECT COALESCE(MAX(seq), 0) + 1 FROM sync_rows)
+	WHERE %s
+	ON CONFLICT(table_name, row_key) DO UPDATE SET
+		ts = MAX(excluded.ts, sync_rows.ts + 1), machine = excluded.machine,
+		deleted = excluded.deleted, seq = excluded.seq;`,
+		strings.ReplaceAll(table, "'", "''"), keyExpr, syncNow, deleted, where)
+}
+
+func installSyncTriggers(ctx context.Context, tx *sql.Tx, m tableMeta, machine string) error {
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = ?`,
+		"arc_sync_"+m.name+"_insert").Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	const when = `WHEN NOT EXISTS (SELECT 1 FROM sync_state WHERE key = 'applying')`
+	oldKey, newKey := syncKeyExpr("OLD", m), syncKeyExpr("NEW", m)
+	table := quoteIdent(m.name)
+	stmts := []string{
+		fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT ON %s %s BEGIN%s\nEND",
+			syncTriggerName(m.name, "insert"), table, when,
+			syncUpsert(m.name, newKey, 0, "1")),
+		// A changed key is a delete of the old row plus an insert of the new one.
+		fmt.Sprintf("CREATE TRIGGER %s AFTER UPDATE ON %s %s BEGIN%s%s\nEND",
+			syncTriggerName(m.name, "update"), table, when,
+			syncUpsert(m.name, oldKey, 1, oldKey+" IS NOT "+newKey),
+			syncUpsert(m.name, newKey, 0, "1")),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER DELETE ON %s %s BEGIN%s\nEND",
+			syncTriggerName(m.name, "delete"), table, when,
+			syncUpsert(m.name, oldKey, 1, "1")),
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	var base int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(seq), 0) FROM sync_rows`).Scan(&base); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT OR IGNORE INTO sync_rows(table_name, row_key, ts, machine, deleted, seq)
+		SELECT ?, %s, 0, ?, 0, ? + ROW_NUMBER() OVER () FROM %s AS NEW`,
+		newKey, table), m.name, machine, base)
+	return err
+}
+
+// pushChanges writes local row versions newer than the export cursor to a new
+// log file in dir/<machine>.
+func pushChanges(ctx context.Context, d *sql.DB, dir, machine string) (int, error) {
+	exported := 0
+	err := WithTx(ctx, d, func(tx *sql.Tx) error {
+		var cursor int64
+		var value string
+		err := tx.QueryRowContext(ctx, `SELECT value FROM sync_state WHERE key = 'exported_seq'`).Scan(&value)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if value != "" {
+			if cursor, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return fmt.Errorf("parse export cursor: %w", err)
+			}
+		}
+
+		type entry struct {
+			table   string
+			key     string
+			ts      int64
+			deleted bool
+			seq     int64
+		}
+		rows, err := tx.QueryContext(ctx, `
+			SELECT table_name, row_key, ts, deleted, seq FROM sync_rows
+			WHERE machine = ? AND seq > ? ORDER BY seq`, machine, cursor)
+		if err != nil {
+			return err
+		}
+		var entries []entry
+		for rows.Next() {
+			var e entry
+			if err := rows.Scan(&e.table, &e.key, &e.ts, &e.deleted, &e.seq); err != nil {
+				rows.Close()
+				return err
+			}
+			entries = append(entries, e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+
+		metas, err := userTables(ctx, tx)
+		if err != nil {
+			return err
+		}
+		byName := make(map[string]tableMeta, len(metas))
+		for _, m := range metas {
+			byName[m.name] = m
+		}
+
+		machineDir := filepath.Join(dir, machine)
+		if err := os.MkdirAll(machineDir, 0o755); err != nil {
+			return err
+		}
+		f, err := os.CreateTemp(machineDir, ".tmp-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		bw := bufio.NewWriter(f)
+		enc := json.NewEncoder(bw)
+
+		for _, e := range entries {
+			m, ok := byName[e.table]
+			if !ok {
+				continue
+			}
+			key, err := decodeKey(e.key)
+			if err != nil || len(key) != len(m.key) {
+				continue
+			}
+			row := make(map[string]any, len(m.columns))
+			for i, k := range m.key {
+				row[k] = key[i]
+			}
+			if !e.deleted {
+				where, args := keyClause(m, row)
+				if row, err = selectRow(ctx, tx, m, where, args); err != nil {
+					return err
+				}
+				if row == nil {
+					continue
+				}
+			}
+
+			if err := writeSyncRefs(ctx, tx, enc, byName, m, row, e.seq); err != nil {
+				return err
+			}
+			c := syncChange{Seq: e.seq, Table: e.table, TS: e.ts, Deleted: e.deleted}
+			for _, v := range key {
+				c.Key = append(c.Key, encodeValue(v))
+			}
+			if !e.deleted {
+				c.Columns = m.columns
+				for _, col := range m.columns {
+					c.Values = append(c.Values, encodeValue(row[col]))
+				}
+			}
+			if err := enc.Encode(c); err != nil {
+				return err
+			}
+			exported++
+		}
+
+		last := entries[len(entries)-1].seq
+		if err := bw.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(f.Name(), filepath.Join(machineDir, fmt.Sprintf("%020d.ndjson", last))); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO sync_state(key, value) VALUES('exported_seq', ?)
+			ON CONFLICT(key) DO UPDATE SET value = excluded.value`, strconv.FormatInt(last, 10))
+		return err
+	})
+	return exported, err
+}
+
+// writeSyncRefs emits the parent rows a change references through locally
+// assigned ids, so the receiver can translate them to its own ids.
+func writeSyncRefs(ctx context.Context, q Querier, enc *json.Encoder, byName map[string]tableMeta, m tableMeta, row map[string]any, seq int64) error {
+	for _, fk := range m.fks {
+		parent, ok := byName[fk.parent]
+		if !ok || parent.autoID == "" || row[fk.column] == nil {
+			continue
+		}
+		col := fk.parentColumn
+		if col == "" {
+			col = parent.autoID
+		}
+		prow, err := selectRow(ctx, q, parent, quoteIdent(col)+" = ?", []any{row[fk.column]})
+		if err != nil {
+			return err
+		}
+		if prow == nil {
+			continue
+		}
+		ref := syncChange{Seq: seq, Table: parent.name, Ref: true, Columns: parent.columns}
+		for _, col := range parent.columns {
+			ref.Values = append(ref.Values, encodeValue(prow[col]))
+		}
+		if err := enc.Encode(ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func selectRow(ctx context.Context, q Querier, m tableMeta, where string, args []any) (map[string]any, error) {
+	values := make([]any, len(m.columns))
+	ptrs := make([]any, len(values))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	err := q.QueryRowContext(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE %s LIMIT 1`,
+		quoteIdents(m.columns), quoteIdent(m.name), where), args...).Scan(ptrs...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	row := make(map[string]any, len(values))
+	for i, c := range m.columns {
+		row[c] = values[i]
+	}
+	return row, nil
+}
+
+func decodeKey(s string) ([]any, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var raw []any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return decodeValues(raw)
+}
+
+func decodeValues(raw []any) ([]any, error) {
+	out := make([]any, len(raw))
+	for i, v := range raw {
+		dv, err := decodeValue(v)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = dv
+	}
+	return out, nil
+}
+
+// pullChanges merges the logs of every other machine in dir.
+func pullChanges(ctx context.Context, d *sql.DB, dir, self string, report *SyncReport) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == self || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		report.Peers = append(report.Peers, e.Name())
+		if err := pullPeer(ctx, d, filepath.Join(dir, e.Name()), e.Name(), report); err != nil {
+			return fmt.Errorf("peer %s: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
+
+func pullPeer(ctx context.Context, d *sql.DB, peerDir, peer string, report *SyncReport) error {
+	var cursor int64
+	err := d.QueryRowContext(ctx, `SELECT last_seq FROM sync_peers WHERE machine = ?`, peer).Scan(&cursor)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	files, err := peerLogFiles(peerDir, cursor)
+	if err != nil || len(files) == 0 {
+		return err
+	}
+
+	return WithTx(ctx, d, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "PRAGMA defer_foreign_keys = ON"); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO sync_state(key, value) VALUES('applying', ?)`, peer); err != nil {
+			return err
+		}
+		metas, err := userTables(ctx, tx)
+		if err != nil {
+			return err
+		}
+		byName := make(map[string]tableMeta, len(metas))
+		for _, m := range metas {
+			byName[m.name] = m
+		}
+
+		w := newRowWriter(tx)
+		last := cursor
+		changed := false
+		for _, path := range files {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			dec := json.NewDecoder(bufio.NewReader(f))
+			dec.UseNumber()
+			for {
+				var c syncChange
+				if err := dec.Decode(&c); errors.Is(err, io.EOF) {
+					break
+				} else if err != nil {
+					f.Close()
+					return fmt.Errorf("read %s: %w", filepath.Base(path), err)
+				}
+				if c.Seq <= cursor {
+					continue
+				}
+				m, ok := byName[c.Table]
+				if !ok {
+					continue
+				}
+				action, err := applySyncChange(ctx, tx, w, m, peer, c)
+				if err != nil {
+					f.Close()
+					return fmt.Errorf("apply %s: %w", c.Table, err)
+				}
+				if !c.Ref {
+					switch action {
+					case syncApplied:
+						report.Applied++
+						changed = true
+					case syncDeleted:
+						report.Deleted++
+						changed = true
+					default:
+						report.Skipped++
+					}
+				}
+				if c.Seq > last {
+					last = c.Seq
+				}
+			}
+			f.Close()
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM sync_state WHERE key = 'applying'`); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO sync_peers(machine, last_seq, synced_at) VALUES(?, ?, ?)
+			ON CONFLICT(machine) DO UPDATE SET last_seq = excluded.last_seq, synced_at = excluded.synced_at`,
+			peer, last, time.Now().Unix()); err != nil {
+			return err
+		}
+		if changed {
+			return rebuildFTS(ctx, tx)
+		}
+		return nil
+	})
+}
+
+// peerLogFiles lists a peer's log files that may hold changes after cursor,
+// oldest first. Each file is named after the last sequence number it holds.
+func peerLogFiles(peerDir string, cursor int64) ([]string, error) {
+	entries, err := os.ReadDir(peerDir)
+	if err != nil {
+		return nil, err
+	}
+	type logFile struct {
+		path string
+		last int64
+	}
+	var files []logFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".ndjson") {
+			continue
+		}
+		last, err := strconv.ParseInt(strings.TrimSuffix(name, ".ndjson"), 10, 64)
+		if err != nil || last <= cursor {
+			continue
+		}
+		files = append(files, logFile{filepath.Join(peerDir, name), last})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].last < files[j].last })
+	out := make([]string, len(files))
+	for i, f := range files {
+		out[i] = f.path
+	}
+	return out, nil
+}
+
+type syncAction int
+
+const (
+	syncSkipped syncAction = iota
+	syncApplied
+	syncDeleted
+)
+
+func applySyncChange(ctx context.Context, tx *sql.Tx, w *rowWriter, m tableMeta, peer string, c syncChange) (syncAction, error) {
+	row := make(map[string]any, len(m.columns))
+	for i, col := range c.Columns {
+		if i >= len(c.Values) || !m.hasColumn(col) {
+			continue
+		}
+		v, err := decodeValue(c.Values[i])
+		if err != nil {
+			return syncSkipped, err
+		}
+		row[col] = v
+	}
+	if c.Ref {
+		// Only establishes the id mapping; the parent's own change carries
+		// its content.
+		_, err := w.apply(ctx, m, row, ConflictSkip)
+		return syncSkipped, err
+	}
+
+	if c.Deleted {
+		key, err := decodeValues(c.Key)
+		if err != nil || len(key) != len(m.key) {
+			return syncSkipped, err
+		}
+		for i, k := range m.key {
+			row[k] = key[i]
+		}
+	}
+	w.remapForeignKeys(m, row)
+
+	keyArgs := make([]any, len(m.key))
+	for i, k := range m.key {
+		keyArgs[i] = row[k]
+	}
+	var rowKey string
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(keyArgs)), ",")
+	if err := tx.QueryRowContext(ctx, "SELECT json_array("+placeholders+")", keyArgs...).Scan(&rowKey); err != nil {
+		return syncSkipped, err
+	}
+
+	var localTS int64
+	var localMachine string
+	err := tx.QueryRowContext(ctx, `SELECT ts, machine FROM sync_rows WHERE table_name = ? AND row_key = ?`,
+		m.name, rowKey).Scan(&localTS, &localMachine)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return syncSkipped, err
+	case localTS > c.TS || (localTS == c.TS && localMachine >= peer):
+		return syncSkipped, nil
+	}
+
+	action := syncApplied
+	if c.Deleted {
+		where, args := keyClause(m, row)
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s`, quoteIdent(m.name), where), args...); err != nil {
+			return syncSkipped, err
+		}
+		action = syncDeleted
+	} else if _, err := w.upsert(ctx, m, row, ConflictOverwrite); err != nil {
+		return syncSkipped, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO sync_rows(table_name, row_key, ts, machine, deleted) VALUES(?, ?, ?, ?, ?)
+		ON CONFLICT(table_name, row_key) DO UPDATE SET
+			ts = excluded.ts, machine = excluded.machine, deleted = excluded.deleted`,
+		m.name, rowKey, c.TS, peer, c.Deleted)
+	return action, err
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestSyncConverges(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	a := openExportDB(t, "a.db")
+	b := openExportDB(t, "b.db")
+
+	// Rows that predate sync on one side, and a colliding tag id on the other.
+	mustExec(t, a,
+		`INSERT INTO items(id, slug, title, type) VALUES('i1', 'one', 'First', 'note')`,
+		`INSERT INTO tags(id, name) VALUES(7, 'go')`,
+		`INSERT INTO item_tags(item_id, tag_id) VALUES('i1', 7)`,
+	)
+	mustExec(t, b, `INSERT INTO tags(id, name) VALUES(7, 'rust')`)
+
+	syncAs := func(d *sql.DB, id string) *SyncReport {
+		t.Helper()
+		r, err := Sync(ctx, d, SyncOptions{Dir: dir, MachineID: id})
+		if err != nil {
+			t.Fatalf("Sync %s: %v", id, err)
+		}
+		return r
+	}
+	syncAs(a, "alpha")
+	mustExec(t, a, `INSERT INTO sessions(id, agent, path, last_user) VALUES('s1', 'claude', '/tmp/s1', 'hello world')`)
+	syncAs(a, "alpha")
+	if r := syncAs(b, "beta"); r.Applied == 0 {
+		t.Fatalf("beta applied nothing: %+v", r)
+	}
+
+	var tag string
+	if err := b.QueryRow(`SELECT t.name FROM item_tags it JOIN tags t ON t.id = it.tag_id WHERE it.item_id = 'i1'`).Scan(&tag); err != nil {
+		t.Fatalf("item_tags on beta: %v", err)
+	}
+	if tag != "go" {
+		t.Fatalf("item tag on beta = %q, want go", tag)
+	}
+
+	// Concurrent edits: beta's title edit is later and must win everywhere;
+	// beta's delete must propagate as a tombstone.
+	mustExec(t, a, `UPDATE items SET title = 'Alpha edit' WHERE id = 'i1'`)
+	time.Sleep(5 * time.Millisecond)
+	mustExec(t, b,
+		`UPDATE items SET title = 'Beta edit' WHERE id = 'i1'`,
+		`DELETE FROM sessions WHERE id = 's1'`,
+	)
+	syncAs(a, "alpha")
+	syncAs(b, "beta")
+	syncAs(a, "alpha")
+
+	for name, d := range map[string]*sql.DB{"alpha": a, "beta": b} {
+		var title string
+		if err := d.QueryRow(`SELECT title FROM items WHERE id = 'i1'`).Scan(&title); err != nil {
+			t.Fatalf("%s title: %v", name, err)
+		}
+		if title != "Beta edit" {
+			t.Fatalf("%s title = %q, want Beta edit", name, title)
+		}
+		var sessions int
+		if err := d.QueryRow(`SELECT COUNT(*) FROM sessions`).Scan(&sessions); err != nil {
+			t.Fatalf("%s sessions: %v", name, err)
+		}
+		if sessions != 0 {
+			t.Fatalf("%s sessions = %d, want tombstoned", name, sessions)
+		}
+	}
+
+	// A settled pair exchanges nothing further.
+	if r := syncAs(a, "alpha"); r.Exported != 0 || r.Applied != 0 {
+		t.Fatalf("expected idle sync, got %+v", r)
+	}
+	if _, err := Sync(ctx, a, SyncOptions{Dir: dir, MachineID: "gamma"}); err == nil {
+		t.Fatalf("expected machine id mismatch error")
+	}
+}
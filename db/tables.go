@@ -0,0 +1,314 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// naturalKeys identifies rows by content rather than by their integer id for
+// tables whose ids are assigned locally and so differ between machines. On
+// import the id is reassigned and references to it are rewritten.
+var naturalKeys = map[string][]string{
+	"tags":              {"name"},
+	"topics":            {"name"},
+	"repo_dependencies": {"repo_name", "dependency_name", "ecosystem"},
+	"repo_analysis":     {"repo_name", "analysis_type", "analyzed_at", "analyzed_by"},
+	"prompt_usage":      {"prompt_name", "model", "timestamp"},
+	"plugin_runs":       {"plugin_name", "target", "timestamp"},
+	"tmux_runs":         {"ts", "pane_id", "command"},
+	"events":            {"ts", "type", "payload"},
+	"session_snapshots": {"session_id", "pane_id", "snapshot_time"},
+	"stale_session_log": {"session_id", "detected_at"},
+}
+
+// rowTimestamps lists the columns that record when a row last changed; the
+// greatest of them decides newest-wins conflicts.
+var rowTimestamps = map[string][]string{
+	"items":             {"updated_at"},
+	"attributes":        {"updated_at"},
+	"sessions":          {"mod_ts"},
+	"external_repos":    {"updated_at", "last_opened_at", "last_commit_at", "added_at"},
+	"env_backups":       {"mtime"},
+	"reading_status":    {"last_accessed", "completed_at", "started_at"},
+	"papers":            {"fetched_at", "updated_date"},
+	"prompt_metadata":   {"last_modified"},
+	"tmux_sessions":     {"last_activity"},
+	"repo_analysis":     {"analyzed_at"},
+	"repo_dependencies": {"detected_at"},
+	"repo_enrichment":   {"checked_at"},
+	"repo_redirects":    {"created_at"},
+	"links":             {"ts"},
+}
+
+// excludedTables are bookkeeping tables that never leave the machine.
+var excludedTables = map[string]bool{
+	"schema_migrations": true,
+	"sync_state":        true,
+	"sync_rows":         true,
+	"sync_peers":        true,
+	// Module state and caches; secrets in it are sealed with a per-machine key.
+	"kv_store": true,
+	"kv_meta":  true,
+}
+
+type foreignKey struct {
+	column       string
+	parent       string
+	parentColumn string
+}
+
+// tableMeta describes how rows of a user table are identified and copied.
+type tableMeta struct {
+	name       string
+	columns    []string
+	key        []string
+	autoID     string
+	fks        []foreignKey
+	timestamps []string
+}
+
+// userTables returns metadata for every ordinary table in the main schema,
+// skipping SQLite internals, FTS indexes and their shadow tables. Parents are
+// ordered before the tables that reference them.
+func userTables(ctx context.Context, q Querier) ([]tableMeta, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT name FROM pragma_table_list
+		WHERE schema = 'main' AND type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if !excludedTables[name] {
+			names = append(names, name)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	metas := make([]tableMeta, 0, len(names))
+	for _, name := range names {
+		m, err := loadTableMeta(ctx, q, name)
+		if err != nil {
+			return nil, err
+		}
+		metas = append(metas, m)
+	}
+	return sortByDependency(metas), nil
+}
+
+func loadTableMeta(ctx context.Context, q Querier, name string) (tableMeta, error) {
+	m := tableMeta{name: name, timestamps: rowTimestamps[name]}
+
+	rows, err := q.QueryContext(ctx, `SELECT name, type, pk FROM pragma_table_info(?) ORDER BY cid`, name)
+	if err != nil {
+		return m, fmt.Errorf("table info %s: %w", name, err)
+	}
+	type pkCol struct {
+		name string
+		typ  string
+		pos  int
+	}
+	var pks []pkCol
+	for rows.Next() {
+		var col, typ string
+		var pk int
+		if err := rows.Scan(&col, &typ, &pk); err != nil {
+			rows.Close()
+			return m, err
+		}
+		m.columns = append(m.columns, col)
+		if pk > 0 {
+			pks = append(pks, pkCol{col, typ, pk})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return m, err
+	}
+	sort.Slice(pks, func(i, j int) bool { return pks[i].pos < pks[j].pos })
+
+	if nk, ok := naturalKeys[name]; ok {
+		m.key = nk
+		if len(pks) == 1 && strings.EqualFold(pks[0].typ, "INTEGER") {
+			m.autoID = pks[0].name
+		}
+	} else if len(pks) > 0 {
+		for _, p := range pks {
+			m.key = append(m.key, p.name)
+		}
+	} else {
+		// No declared key: rowids are local, so the row's content is its identity.
+		m.key = m.columns
+	}
+
+	fkRows, err := q.QueryContext(ctx, `SELECT "table", "from", "to" FROM pragma_foreign_key_list(?)`, name)
+	if err != nil {
+		return m, fmt.Errorf("foreign keys %s: %w", name, err)
+	}
+	defer fkRows.Close()
+	for fkRows.Next() {
+		var fk foreignKey
+		var to sql.NullString
+		if err := fkRows.Scan(&fk.parent, &fk.column, &to); err != nil {
+			return m, err
+		}
+		fk.parentColumn = to.String
+		m.fks = append(m.fks, fk)
+	}
+	return m, fkRows.Err()
+}
+
+// sortByDependency orders tables so referenced tables come first, keeping
+// alphabetical order otherwise.
+func sortByDependency(metas []tableMeta) []tableMeta {
+	byName := make(map[string]tableMeta, len(metas))
+	for _, m := range metas {
+		byName[m.name] = m
+	}
+	var out []tableMeta
+	state := make(map[string]int) // 0 unvisited, 1 visiting, 2 done
+	var visit func(name string)
+	visit = func(name string) {
+		m, ok := byName[name]
+		if !ok || state[name] != 0 {
+			return
+		}
+		state[name] = 1
+		for _, fk := range m.fks {
+			if fk.parent != name {
+				visit(fk.parent)
+			}
+		}
+		state[name] = 2
+		out = append(out, m)
+	}
+	for _, m := range metas {
+		visit(m.name)
+	}
+	return out
+}
+
+func (m tableMeta) hasColumn(name string) bool {
+	for _, c := range m.columns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteIdent quotes an SQLite identifier.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteIdents(names []string) string {
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = quoteIdent(n)
+	}
+	return strings.Join(out, ", ")
+}
+
+// encodeValue converts a scanned SQLite value into a JSON-safe value. Blobs
+// become {"$b64": "..."} so they survive the round trip.
+func encodeValue(v any) any {
+	if b, ok := v.([]byte); ok {
+		return map[string]string{"$b64": base64.StdEncoding.EncodeToString(b)}
+	}
+	return v
+}
+
+// decodeValue reverses encodeValue for values decoded with UseNumber.
+func decodeValue(v any) (any, error) {
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i, nil
+		}
+		return x.Float64()
+	case map[string]any:
+		s, ok := x["$b64"].(string)
+		if !ok {
+			return nil, fmt.Errorf("unsupported object value %v", x)
+		}
+		return base64.StdEncoding.DecodeString(s)
+	default:
+		return v, nil
+	}
+}
+
+// compareValues orders two SQLite values: NULL first, numbers numerically,
+// everything else by string form.
+func compareValues(a, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+	af, aNum := toFloat(a)
+	bf, bNum := toFloat(b)
+	if aNum && bNum {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toFloat(v any) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// maxValue returns the greatest of the named columns in row.
+func maxValue(row map[string]any, cols []string) any {
+	var best any
+	for _, c := range cols {
+		if v := row[c]; compareValues(v, best) > 0 {
+			best = v
+		}
+	}
+	return best
+}
+
+// idKey normalises an id value for use as a map key.
+func idKey(v any) string {
+	return fmt.Sprintf("%T:%v", v, v)
+}
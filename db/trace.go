@@ -0,0 +1,376 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// QueryEvent describes a single statement run through a traced handle.
+type QueryEvent struct {
+	// Kind is "exec" or "query".
+	Kind     string
+	Query    string
+	Duration time.Duration
+	// Rows is rows affected for exec and rows read for query.
+	Rows int64
+	Err  error
+	Slow bool
+}
+
+// Tracer records every statement executed through a handle opened with
+// Options.Tracer: it logs to slog, flags slow queries and keeps counters.
+// A Tracer may be shared by several handles.
+type Tracer struct {
+	// Logger receives a debug record per statement and a warning for slow or
+	// failed ones. Nil disables logging.
+	Logger *slog.Logger
+	// SlowThreshold flags statements that take at least this long (0 disables).
+	SlowThreshold time.Duration
+	// OnQuery, if set, is called synchronously after every statement.
+	OnQuery func(QueryEvent)
+
+	mu    sync.Mutex
+	stats map[string]*QueryStats
+}
+
+// QueryStats aggregates events for one normalised statement.
+type QueryStats struct {
+	Query         string        `json:"query"`
+	Count         int64         `json:"count"`
+	Errors        int64         `json:"errors"`
+	Slow          int64         `json:"slow"`
+	Rows          int64         `json:"rows"`
+	TotalDuration time.Duration `json:"total_duration_ns"`
+	MaxDuration   time.Duration `json:"max_duration_ns"`
+}
+
+// TraceStats is a snapshot of a Tracer's counters.
+type TraceStats struct {
+	Queries       int64         `json:"queries"`
+	Errors        int64         `json:"errors"`
+	Slow          int64         `json:"slow"`
+	TotalDuration time.Duration `json:"total_duration_ns"`
+	// Statements is ordered by total duration, slowest first.
+	Statements []QueryStats `json:"statements"`
+}
+
+// NewTracer returns a Tracer that logs to logger and flags statements slower
+// than slow.
+func NewTracer(logger *slog.Logger, slow time.Duration) *Tracer {
+	return &Tracer{Logger: logger, SlowThreshold: slow}
+}
+
+// Stats returns a snapshot of the counters collected so far.
+func (t *Tracer) Stats() TraceStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out TraceStats
+	for _, s := range t.stats {
+		out.Queries += s.Count
+		out.Errors += s.Errors
+		out.Slow += s.Slow
+		out.TotalDuration += s.TotalDuration
+		out.Statements = append(out.Statements, *s)
+	}
+	sort.Slice(out.Statements, func(i, j int) bool {
+		return out.Statements[i].TotalDuration > out.Statements[j].TotalDuration
+	})
+	return out
+}
+
+// Reset clears all counters.
+func (t *Tracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats = nil
+}
+
+func (t *Tracer) record(ev QueryEvent) {
+	if t == nil {
+		return
+	}
+	ev.Slow = t.SlowThreshold > 0 && ev.Duration >= t.SlowThreshold
+	query := normalizeQuery(ev.Query)
+
+	t.mu.Lock()
+	if t.stats == nil {
+		t.stats = make(map[string]*QueryStats)
+	}
+	s, ok := t.stats[query]
+	if !ok {
+		s = &QueryStats{Query: query}
+		t.stats[query] = s
+	}
+	s.Count++
+	s.Rows += ev.Rows
+	s.TotalDuration += ev.Duration
+	if ev.Duration > s.MaxDuration {
+		s.MaxDuration = ev.Duration
+	}
+	if ev.Err != nil {
+		s.Errors++
+	}
+	if ev.Slow {
+		s.Slow++
+	}
+	t.mu.Unlock()
+
+	if t.Logger != nil {
+		attrs := []slog.Attr{
+			slog.String("kind", ev.Kind),
+			slog.String("query", query),
+			slog.Duration("duration", ev.Duration),
+			slog.Int64("rows", ev.Rows),
+		}
+		level := slog.LevelDebug
+		msg := "db query"
+		switch {
+		case ev.Err != nil:
+			attrs = append(attrs, slog.Any("error", ev.Err))
+			level, msg = slog.LevelWarn, "db query failed"
+		case ev.Slow:
+			level, msg = slog.LevelWarn, "db slow query"
+		}
+		t.Logger.LogAttrs(context.Background(), level, msg, attrs...)
+	}
+	if t.OnQuery != nil {
+		t.OnQuery(ev)
+	}
+}
+
+// normalizeQuery collapses whitespace so the same statement aggregates
+// regardless of indentation.
+func normalizeQuery(q string) string {
+	return strings.Join(strings.Fields(q), " ")
+}
+
+// tracedConnector opens sqlite connections wrapped with a Tracer, or with
+// none when tracer is nil; either way transactions may begin immediately
+// (see beginTx).
+type tracedConnector struct {
+	dsn    string
+	tracer *Tracer
+}
+
+func (c *tracedConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := (&sqlite.Driver{}).Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, tracer: c.tracer}, nil
+}
+
+func (c *tracedConnector) Driver() driver.Driver { return &sqlite.Driver{} }
+
+type tracedConn struct {
+	driver.Conn
+	tracer *Tracer
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil || c.tracer == nil {
+		return stmt, err
+	}
+	return &tracedStmt{Stmt: stmt, query: query, tracer: c.tracer}, nil
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return beginTx(ctx, c.Conn, opts)
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	c.tracer.record(execEvent(query, start, res, err))
+	return res, err
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	if c.tracer == nil {
+		return rows, err
+	}
+	if err != nil {
+		c.tracer.record(QueryEvent{Kind: "query", Query: query, Duration: time.Since(start), Err: err})
+		return nil, err
+	}
+	return newTracedRows(rows, query, c.tracer, time.Since(start)), nil
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	query  string
+	tracer *Tracer
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		res, err = s.Stmt.Exec(namedToValues(args))
+	}
+	s.tracer.record(execEvent(s.query, start, res, err))
+	return res, err
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(namedToValues(args))
+	}
+	if err != nil {
+		s.tracer.record(QueryEvent{Kind: "query", Query: s.query, Duration: time.Since(start), Err: err})
+		return nil, err
+	}
+	return newTracedRows(rows, s.query, s.tracer, time.Since(start)), nil
+}
+
+// tracedRows accumulates time spent inside the driver and reports the event
+// when the result set is closed.
+type tracedRows struct {
+	driver.Rows
+	query   string
+	tracer  *Tracer
+	elapsed time.Duration
+	n       int64
+	err     error
+	once    sync.Once
+}
+
+func newTracedRows(rows driver.Rows, query string, tracer *Tracer, elapsed time.Duration) *tracedRows {
+	return &tracedRows{Rows: rows, query: query, tracer: tracer, elapsed: elapsed}
+}
+
+func (r *tracedRows) Next(dest []driver.Value) error {
+	start := time.Now()
+	err := r.Rows.Next(dest)
+	r.elapsed += time.Since(start)
+	switch {
+	case err == nil:
+		r.n++
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+	return err
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	r.once.Do(func() {
+		r.tracer.record(QueryEvent{Kind: "query", Query: r.query, Duration: r.elapsed, Rows: r.n, Err: r.err})
+	})
+	return err
+}
+
+func (r *tracedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if c, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return c.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *tracedRows) ColumnTypeLength(index int) (int64, bool) {
+	if c, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return c.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *tracedRows) ColumnTypeNullable(index int) (bool, bool) {
+	if c, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return c.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *tracedRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if c, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return c.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+func (r *tracedRows) ColumnTypeScanType(index int) reflect.Type {
+	if c, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return c.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(any)).Elem()
+}
+
+func execEvent(query string, start time.Time, res driver.Result, err error) QueryEvent {
+	ev := QueryEvent{Kind: "exec", Query: query, Duration: time.Since(start), Err: err}
+	if err == nil && res != nil {
+		ev.Rows, _ = res.RowsAffected()
+	}
+	return ev
+}
+
+func namedToValues(args []driver.NamedValue) []driver.Value {
+	out := make([]driver.Value, len(args))
+	for i, a := range args {
+		out[i] = a.Value
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package db
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracer(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	tracer := NewTracer(logger, time.Nanosecond)
+
+	var events []QueryEvent
+	tracer.OnQuery = func(ev QueryEvent) { events = append(events, ev) }
+
+	handle, err := OpenWithOptions(":memory:", Options{Tracer: tracer})
+	if err != nil {
+		t.Fatalf("OpenWithOptions: %v", err)
+	}
+	defer handle.Close()
+	tracer.Reset()
+	events = nil
+
+	for _, k := range []string{"a", "b", "c"} {
+		if _, err := handle.Exec(`INSERT INTO settings(key, value) VALUES(?, 'v')`, k); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	rows, err := handle.Query(`SELECT key FROM settings`)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	if _, err := handle.Exec(`SELECT * FROM missing_table`); err == nil {
+		t.Fatalf("expected error for missing table")
+	}
+
+	stats := tracer.Stats()
+	if stats.Queries != 5 || stats.Errors != 1 || stats.Slow != 5 {
+		t.Fatalf("stats = %+v, want 5 queries, 1 error, 5 slow", stats)
+	}
+	var insert, sel *QueryStats
+	for i := range stats.Statements {
+		switch {
+		case strings.HasPrefix(stats.Statements[i].Query, "INSERT"):
+			insert = &stats.Statements[i]
+		case strings.HasPrefix(stats.Statements[i].Query, "SELECT key"):
+			sel = &stats.Statements[i]
+		}
+	}
+	if insert == nil || insert.Count != 3 || insert.Rows != 3 {
+		t.Fatalf("insert stats = %+v, want 3 execs affecting 3 rows", insert)
+	}
+	if sel == nil || sel.Rows != 3 {
+		t.Fatalf("select stats = %+v, want 3 rows read", sel)
+	}
+	if len(events) != 5 {
+		t.Fatalf("OnQuery called %d times, want 5", len(events))
+	}
+	if !strings.Contains(buf.String(), "db slow query") || !strings.Contains(buf.String(), "db query failed") {
+		t.Fatalf("log output missing slow/failed records:\n%s", buf.String())
+	}
+}
@@ -6,38 +6,163 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
+// TxOptions configures WithTxOptions.
+type TxOptions struct {
+	// Immediate takes the write lock at BEGIN instead of on the first write,
+	// so a transaction never fails mid-flight upgrading a read lock. It needs
+	// a handle from OpenWithOptions; others fail with ErrImmediateUnsupported.
+	Immediate bool
+	// ReadOnly runs the transaction with PRAGMA query_only, rejecting writes.
+	ReadOnly bool
+	// Retries is how many times to re-run the transaction after SQLITE_BUSY
+	// or SQLITE_LOCKED. fn must be safe to run again after a rollback.
+	Retries int
+	// RetryBackoff is the first retry delay (default 25ms); it doubles per
+	// attempt up to one second.
+	RetryBackoff time.Duration
+	// Timeout bounds the whole call, including retries.
+	Timeout time.Duration
+}
+
+const maxRetryBackoff = time.Second
+
+// ErrImmediateUnsupported is returned for TxOptions.Immediate on a handle
+// not opened by this package, whose connections cannot begin immediately.
+var ErrImmediateUnsupported = errors.New("db: immediate transactions need a handle from OpenWithOptions")
+
+// beginImmediateKey marks a BeginTx context asking for BEGIN IMMEDIATE; its
+// value is a *bool set once the connection has done so.
+type beginImmediateKey struct{}
+
+var savepointSeq atomic.Int64
+
 // WithTx executes fn within a transaction, rolling back on error.
 func WithTx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
+	return WithTxOptions(ctx, db, TxOptions{}, func(_ context.Context, tx *sql.Tx) error {
+		return fn(tx)
+	})
+}
+
+// WithTxTimeout executes fn within a transaction with a timeout context.
+func WithTxTimeout(db *sql.DB, timeout time.Duration, fn func(context.Context, *sql.Tx) error) error {
+	return WithTxOptions(context.Background(), db, TxOptions{Timeout: timeout}, fn)
+}
+
+// WithTxOptions executes fn within a transaction configured by opts, rolling
+// back on error and retrying busy/locked failures with exponential backoff.
+func WithTxOptions(ctx context.Context, db *sql.DB, opts TxOptions, fn func(context.Context, *sql.Tx) error) error {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	backoff := opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = 25 * time.Millisecond
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, db, opts, fn)
+		if err == nil || !IsBusy(err) || attempt >= opts.Retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (gave up retrying: %v)", err, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+// WithSavepoint executes fn inside a SAVEPOINT on an existing transaction, so
+// the work can be rolled back without aborting the caller's transaction.
+func WithSavepoint(ctx context.Context, tx *sql.Tx, fn func(*sql.Tx) error) error {
+	name := fmt.Sprintf("arc_sp_%d", savepointSeq.Add(1))
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
 		return err
 	}
 	defer func() {
 		if p := recover(); p != nil {
-			_ = tx.Rollback()
+			rollbackSavepoint(tx, name)
 			panic(p)
 		}
 	}()
 
 	if err := fn(tx); err != nil {
-		_ = tx.Rollback()
+		rollbackSavepoint(tx, name)
 		return err
 	}
-	return tx.Commit()
+	_, err := tx.ExecContext(ctx, "RELEASE "+name)
+	return err
 }
 
-// WithTxTimeout executes fn within a transaction with a timeout context.
-func WithTxTimeout(db *sql.DB, timeout time.Duration, fn func(context.Context, *sql.Tx) error) error {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+// IsBusy reports whether err is a SQLITE_BUSY or SQLITE_LOCKED error.
+func IsBusy(err error) bool {
+	var se *sqlite.Error
+	if !errors.As(err, &se) {
+		return false
+	}
+	// Extended result codes carry the primary code in the low byte.
+	switch se.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
+	}
+	return false
+}
 
-	tx, err := db.BeginTx(ctx, nil)
+func runTx(ctx context.Context, db *sql.DB, opts TxOptions, fn func(context.Context, *sql.Tx) error) (err error) {
+	conn, err := db.Conn(ctx)
 	if err != nil {
 		return err
 	}
+	// The connection's own query_only setting is restored afterwards, so
+	// connections that were already read-only stay that way.
+	var queryOnly int
+	defer func() {
+		if opts.ReadOnly {
+			if _, rerr := conn.ExecContext(context.Background(), fmt.Sprintf("PRAGMA query_only = %d", queryOnly)); rerr != nil {
+				// Never return a connection in the wrong mode to the pool.
+				_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+		}
+		_ = conn.Close()
+	}()
+
+	if opts.ReadOnly {
+		if err := conn.QueryRowContext(ctx, "PRAGMA query_only").Scan(&queryOnly); err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, "PRAGMA query_only = 1"); err != nil {
+			return err
+		}
+	}
+
+	immediate := opts.Immediate && !opts.ReadOnly
+	began := false
+	beginCtx := ctx
+	if immediate {
+		beginCtx = context.WithValue(ctx, beginImmediateKey{}, &began)
+	}
+	tx, err := conn.BeginTx(beginCtx, &sql.TxOptions{ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return err
+	}
+	if immediate && !began {
+		_ = tx.Rollback()
+		return ErrImmediateUnsupported
+	}
 	defer func() {
 		if p := recover(); p != nil {
 			_ = tx.Rollback()
@@ -51,3 +176,41 @@ func WithTxTimeout(db *sql.DB, timeout time.Duration, fn func(context.Context, *
 	}
 	return tx.Commit()
 }
+
+// beginTx begins a transaction on c, with BEGIN IMMEDIATE when ctx asks for
+// it (see runTx) and otherwise as the DSN's _txlock says.
+func beginTx(ctx context.Context, c driver.Conn, opts driver.TxOptions) (driver.Tx, error) {
+	if began, ok := ctx.Value(beginImmediateKey{}).(*bool); ok && !opts.ReadOnly {
+		if e, ok := c.(driver.ExecerContext); ok {
+			if _, err := e.ExecContext(ctx, "BEGIN IMMEDIATE", nil); err != nil {
+				return nil, err
+			}
+			*began = true
+			return immediateTx{e}, nil
+		}
+	}
+	if b, ok := c.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Begin()
+}
+
+// immediateTx ends a transaction begun with BEGIN IMMEDIATE.
+type immediateTx struct {
+	c driver.ExecerContext
+}
+
+func (t immediateTx) Commit() error {
+	_, err := t.c.ExecContext(context.Background(), "COMMIT", nil)
+	return err
+}
+
+func (t immediateTx) Rollback() error {
+	_, err := t.c.ExecContext(context.Background(), "ROLLBACK", nil)
+	return err
+}
+
+func rollbackSavepoint(tx *sql.Tx, name string) {
+	_, _ = tx.Exec("ROLLBACK TO " + name)
+	_, _ = tx.Exec("RELEASE " + name)
+}
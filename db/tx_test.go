@@ -0,0 +1,213 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithTxOptionsRetriesBusy(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/tx.db"
+	holder, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer holder.Close()
+	// No busy_timeout, so contention surfaces immediately as SQLITE_BUSY.
+	worker, err := OpenWithOptions(path, Options{Pragmas: []Pragma{{"busy_timeout", "0"}}})
+	if err != nil {
+		t.Fatalf("OpenWithOptions: %v", err)
+	}
+	defer worker.Close()
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- WithTxOptions(ctx, holder, TxOptions{Immediate: true}, func(ctx context.Context, tx *sql.Tx) error {
+			close(locked)
+			<-release
+			return nil
+		})
+	}()
+	<-locked
+
+	insert := func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO settings(key, value) VALUES('k', 'v')`)
+		return err
+	}
+
+	err = WithTxOptions(ctx, worker, TxOptions{Immediate: true}, insert)
+	if !IsBusy(err) {
+		t.Fatalf("expected busy error without retries, got %v", err)
+	}
+
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+	if err := WithTxOptions(ctx, worker, TxOptions{Immediate: true, Retries: 10, RetryBackoff: 10 * time.Millisecond}, insert); err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("holder tx: %v", err)
+	}
+}
+
+func TestWithTxOptionsReadOnly(t *testing.T) {
+	ctx := context.Background()
+	handle, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer handle.Close()
+	handle.SetMaxOpenConns(1)
+
+	err = WithTxOptions(ctx, handle, TxOptions{ReadOnly: true}, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO settings(key, value) VALUES('k', 'v')`)
+		return err
+	})
+	if err == nil {
+		t.Fatalf("expected write in read-only tx to fail")
+	}
+
+	// The pooled connection must be writable again afterwards.
+	if _, err := handle.Exec(`INSERT INTO settings(key, value) VALUES('k', 'v')`); err != nil {
+		t.Fatalf("write after read-only tx: %v", err)
+	}
+}
+
+func TestWithSavepoint(t *testing.T) {
+	ctx := context.Background()
+	handle, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer handle.Close()
+
+	errInner := errors.New("inner failed")
+	err = WithTx(ctx, handle, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`INSERT INTO settings(key, value) VALUES('outer', '1')`); err != nil {
+			return err
+		}
+		err := WithSavepoint(ctx, tx, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`INSERT INTO settings(key, value) VALUES('inner', '1')`); err != nil {
+				return err
+			}
+			return errInner
+		})
+		if !errors.Is(err, errInner) {
+			t.Fatalf("WithSavepoint error = %v, want %v", err, errInner)
+		}
+		return WithSavepoint(ctx, tx, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`INSERT INTO settings(key, value) VALUES('nested', '1')`)
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	var keys []string
+	rows, err := handle.Query(`SELECT key FROM settings ORDER BY key`)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) != 2 || keys[0] != "nested" || keys[1] != "outer" {
+		t.Fatalf("keys = %v, want [nested outer]", keys)
+	}
+}
+
+func TestWithTxOptionsImmediateWithoutMigrations(t *testing.T) {
+	ctx := context.Background()
+	handle, err := OpenWithOptions(t.TempDir()+"/raw.db", Options{SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("OpenWithOptions: %v", err)
+	}
+	defer handle.Close()
+
+	err = WithTxOptions(ctx, handle, TxOptions{Immediate: true}, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `CREATE TABLE t(x)`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("immediate tx without schema_migrations: %v", err)
+	}
+
+	raw, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer raw.Close()
+	err = WithTxOptions(ctx, raw, TxOptions{Immediate: true}, func(context.Context, *sql.Tx) error { return nil })
+	if !errors.Is(err, ErrImmediateUnsupported) {
+		t.Fatalf("immediate tx on raw handle = %v, want ErrImmediateUnsupported", err)
+	}
+}
+
+func TestWithTxOptionsReadOnlyKeepsReaderReadOnly(t *testing.T) {
+	ctx := context.Background()
+	pool, err := OpenPool(":memory:", Options{})
+	if err != nil {
+		t.Fatalf("OpenPool: %v", err)
+	}
+	defer pool.Close()
+	pool.Reader.SetMaxOpenConns(1)
+
+	err = WithTxOptions(ctx, pool.Reader, TxOptions{ReadOnly: true}, func(ctx context.Context, tx *sql.Tx) error {
+		var n int
+		return tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM settings`).Scan(&n)
+	})
+	if err != nil {
+		t.Fatalf("read-only tx: %v", err)
+	}
+	if _, err := pool.Reader.Exec(`INSERT INTO settings(key, value) VALUES('k', 'v')`); err == nil {
+		t.Fatalf("reader became writable after a read-only tx")
+	}
+}
+
+func TestInTxConn(t *testing.T) {
+	ctx := context.Background()
+	handle, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer handle.Close()
+	conn, err := handle.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn: %v", err)
+	}
+	defer conn.Close()
+
+	errFail := errors.New("fail")
+	err = InTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`INSERT INTO settings(key, value) VALUES('rolled', 'back')`); err != nil {
+			return err
+		}
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("InTx error = %v, want %v", err, errFail)
+	}
+	if err := InTx(ctx, conn, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO settings(key, value) VALUES('k', 'v')`)
+		return err
+	}); err != nil {
+		t.Fatalf("InTx on conn: %v", err)
+	}
+	var keys string
+	if err := conn.QueryRowContext(ctx, `SELECT group_concat(key) FROM settings`).Scan(&keys); err != nil || keys != "k" {
+		t.Fatalf("keys = %q, %v", keys, err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourorg/arc-sdk/db"
+	"github.com/yourorg/arc-sdk/store"
+)
+
+func TestEnrich(t *testing.T) {
+	ctx := context.Background()
+	d, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d.Close()
+	repos := store.NewReposStore(d)
+	for _, url := range []string{
+		"https://github.com/acme/one",
+		"git@github.com:acme/two.git",
+		"https://gitlab.com/group/proj",
+		"https://codeberg.org/org/tool",
+		"https://example.org/x/y",
+	} {
+		name := url[strings.LastIndex(url, "/")+1:]
+		name = strings.TrimSuffix(name, ".git")
+		err := repos.Upsert(ctx, store.Repo{Name: name, URL: url, License: "local", AddedAt: 1})
+		if err != nil {
+			t.Fatalf("Upsert %s: %v", name, err)
+		}
+	}
+
+	var mu sync.Mutex
+	var requests []string
+	limitTwo := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.EscapedPath())
+		limited := limitTwo
+		mu.Unlock()
+
+		switch r.URL.EscapedPath() {
+		case "/repos/acme/one", "/repos/acme/two":
+			if r.Header.Get("Authorization") != "Bearer gh-token" {
+				t.Errorf("GitHub auth header = %q", r.Header.Get("Authorization"))
+			}
+			if limited && strings.HasSuffix(r.URL.Path, "/two") {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", fmt.Sprint(time.Now().Add(time.Hour).Unix()))
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("X-RateLimit-Remaining", "100")
+			// Forges report the canonical case, which is not a move.
+			fmt.Fprintf(w, `{"html_url":"https://github.com/Acme/%s","description":"the one","language":"Go","topics":["cli","go"],
+				"stargazers_count":42,"forks_count":3,"homepage":"https://one.dev",
+				"default_branch":"main","archived":false,
+				"license":{"spdx_id":"NOASSERTION","name":"Custom"}}`, strings.TrimPrefix(r.URL.Path, "/repos/acme/"))
+		case "/api/v4/projects/group%2Fproj":
+			if r.Header.Get("PRIVATE-TOKEN") != "gl-token" {
+				t.Errorf("GitLab token header = %q", r.Header.Get("PRIVATE-TOKEN"))
+			}
+			fmt.Fprint(w, `{"description":"proj","tag_list":["infra"],"star_count":7,"forks_count":1,
+				"default_branch":"trunk","archived":true,"license":{"nickname":"GNU GPLv3","name":"GNU General Public License v3.0"}}`)
+		case "/api/v4/projects/group%2Fproj/languages":
+			fmt.Fprint(w, `{"Shell":12.5,"Rust":80.1,"Python":7.4}`)
+		case "/api/v1/repos/org/tool", "/api/v1/repos/org/tool-ng":
+			if r.Header.Get("Authorization") != "token gt-token" {
+				t.Errorf("Gitea auth header = %q", r.Header.Get("Authorization"))
+			}
+			fmt.Fprint(w, `{"html_url":"https://codeberg.org/org/tool-ng","description":"tool","language":"Zig","topics":[],"stars_count":5,
+				"forks_count":0,"website":"https://tool.org","default_branch":"main","licenses":["MIT"]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	e := NewEnricher(repos, map[string]MetadataProvider{
+		"github.com":   NewGitHub(ProviderOptions{BaseURL: srv.URL, Token: "gh-token"}),
+		"gitlab.com":   NewGitLab(ProviderOptions{BaseURL: srv.URL, Token: "gl-token"}),
+		"codeberg.org": NewGitea(ProviderOptions{BaseURL: srv.URL + "/", Token: "gt-token"}),
+	})
+	outcomes, err := e.Enrich(ctx, []string{"one", "two", "proj", "tool", "y", "missing"})
+	if err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	for _, o := range outcomes[:4] {
+		if !o.Updated || o.Err != nil {
+			t.Fatalf("outcome %+v, want updated", o)
+		}
+	}
+	if outcomes[0].MovedTo != "" || outcomes[3].MovedTo != "https://codeberg.org/org/tool-ng" {
+		t.Fatalf("moves = %q, %q", outcomes[0].MovedTo, outcomes[3].MovedTo)
+	}
+	if !errors.Is(outcomes[4].Err, ErrNoProvider) || outcomes[5].Err == nil {
+		t.Fatalf("outcomes for y, missing = %+v, %+v", outcomes[4], outcomes[5])
+	}
+
+	one, _ := repos.Get(ctx, "one")
+	if one.Stars != 42 || one.License != "Custom" || one.Homepage != "https://one.dev" ||
+		strings.Join(one.Topics, ",") != "cli,go" || one.EnrichedAt == 0 {
+		t.Fatalf("enriched one = %+v", one)
+	}
+	proj, _ := repos.Get(ctx, "proj")
+	if proj.Language != "Rust" || !proj.Archived || proj.DefaultBranch != "trunk" ||
+		strings.Join(proj.Topics, ",") != "infra" || proj.License != "GNU GPLv3" {
+		t.Fatalf("enriched proj = %+v", proj)
+	}
+	tool, _ := repos.GetByURL(ctx, "https://codeberg.org/org/tool")
+	if tool.URL != "https://codeberg.org/org/tool-ng" || tool.Language != "Zig" || tool.Homepage != "https://tool.org" || tool.License != "MIT" || len(tool.Topics) != 0 {
+		t.Fatalf("enriched tool = %+v", tool)
+	}
+	if y, _ := repos.Get(ctx, "y"); y.EnrichedAt != 0 || y.License != "local" {
+		t.Fatalf("unenriched y = %+v", y)
+	}
+
+	// Unchanged repos are revalidated with their ETag.
+	outcomes, err = e.Enrich(ctx, []string{"one"})
+	if err != nil || !outcomes[0].NotModified || outcomes[0].Updated {
+		t.Fatalf("re-Enrich = %+v, %v", outcomes, err)
+	}
+
+	// An exhausted limit skips the host's remaining repos without requests.
+	mu.Lock()
+	limitTwo = true
+	requests = nil
+	mu.Unlock()
+	outcomes, err = e.Enrich(ctx, []string{"two", "one", "tool"})
+	if err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	var rlErr *RateLimitError
+	if !errors.As(outcomes[0].Err, &rlErr) || time.Until(rlErr.Reset) < 50*time.Minute {
+		t.Fatalf("rate limited outcome = %+v", outcomes[0])
+	}
+	if !errors.Is(outcomes[1].Err, ErrRateLimited) || outcomes[2].Err != nil {
+		t.Fatalf("outcomes after limit = %+v", outcomes[1:])
+	}
+	if strings.Join(requests, " ") != "/repos/acme/two /api/v1/repos/org/tool-ng" {
+		t.Fatalf("requests = %v", requests)
+	}
+	rec, err := repos.Enrichment(ctx, "two")
+	if err != nil || rec.Error == "" || rec.ETag != `"v1"` || rec.EnrichedAt == 0 {
+		t.Fatalf("failed enrichment record = %+v, %v", rec, err)
+	}
+
+	stale, err := repos.NotEnrichedSince(ctx, time.Now().Add(time.Hour).Unix())
+	if err != nil || len(stale) != 5 || stale[0].Name != "y" {
+		t.Fatalf("NotEnrichedSince = %v, %v", stale, err)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package enrich
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/arc-sdk/db"
+	"github.com/yourorg/arc-sdk/git"
+	"github.com/yourorg/arc-sdk/store"
+)
+
+// Enricher updates repos from their forges' metadata.
+type Enricher struct {
+	Repos *store.ReposStore
+	// Providers maps a forge host, e.g. "github.com", to its provider.
+	Providers map[string]MetadataProvider
+	// MaxWait is how long Enrich will sleep for a host's rate limit to
+	// reset; hosts limited for longer are skipped. 0 never waits.
+	MaxWait time.Duration
+
+	mu sync.Mutex
+	// limited holds, per host, when its exhausted rate limit resets.
+	limited map[string]time.Time
+}
+
+// NewEnricher creates an Enricher writing to repos.
+func NewEnricher(repos *store.ReposStore, providers map[string]MetadataProvider) *Enricher {
+	return &Enricher{Repos: repos, Providers: providers}
+}
+
+// Outcome is what Enrich did for one repo.
+type Outcome struct {
+	Name string
+	// Host is the forge host the repo was routed to.
+	Host string
+	// Updated is set when new metadata was written.
+	Updated bool
+	// NotModified is set when the forge reported no change since the last
+	// enrichment.
+	NotModified bool
+	// MovedTo is the repo's new URL if the forge reported it moved; the
+	// store has recorded the move (see store.ReposStore.Move).
+	MovedTo string
+	// Err is why the repo was not enriched.
+	Err error
+}
+
+// Enrich fetches metadata for the named repos, one at a time, and writes it
+// to the store along with when each repo was enriched. Repos the forge
+// reports as renamed are moved to their new URL. Requests carry the
+// ETag of the previous enrichment, so unchanged repos cost little or nothing
+// against the forge's rate limit. Once a host's limit is exhausted its
+// remaining repos are skipped with a *RateLimitError, unless the reset is
+// within MaxWait.
+//
+// Per-repo failures are reported in the outcomes; the error is only for
+// cancellation and store failures.
+func (e *Enricher) Enrich(ctx context.Context, names []string) ([]Outcome, error) {
+	outcomes := make([]Outcome, 0, len(names))
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return outcomes, err
+		}
+		out, err := e.enrichOne(ctx, name)
+		if err != nil {
+			return outcomes, err
+		}
+		outcomes = append(outcomes, out)
+	}
+	return outcomes, nil
+}
+
+func (e *Enricher) enrichOne(ctx context.Context, name string) (Outcome, error) {
+	out := Outcome{Name: name}
+	repo, err := e.Repos.Get(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		out.Err = fmt.Errorf("repo %s: %w", name, err)
+		return out, nil
+	}
+	if err != nil {
+		return out, fmt.Errorf("get repo %s: %w", name, err)
+	}
+
+	parsed, err := git.ParseGitURL(repo.URL)
+	if err != nil {
+		out.Err = err
+		return out, nil
+	}
+	out.Host = strings.ToLower(parsed.Host)
+	provider, ok := e.Providers[out.Host]
+	if !ok {
+		out.Err = fmt.Errorf("%w %s", ErrNoProvider, out.Host)
+		return out, nil
+	}
+	owner, repoName := repo.Owner, repo.Repo
+	if owner == "" || repoName == "" {
+		owner, repoName = parsed.Owner, parsed.Name
+	}
+
+	if err := e.waitForLimit(ctx, out.Host); err != nil {
+		if ctx.Err() != nil {
+			return out, ctx.Err()
+		}
+		out.Err = err
+		return out, nil
+	}
+
+	var etag string
+	prev, err := e.Repos.Enrichment(ctx, name)
+	switch {
+	case err == nil && prev.Provider == out.Host:
+		etag = prev.ETag
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return out, fmt.Errorf("get enrichment for %s: %w", name, err)
+	}
+
+	now := time.Now().Unix()
+	res, fetchErr := provider.Fetch(ctx, owner, repoName, etag)
+	if fetchErr != nil {
+		if ctx.Err() != nil {
+			return out, ctx.Err()
+		}
+		var rlErr *RateLimitError
+		if errors.As(fetchErr, &rlErr) {
+			e.limit(out.Host, rlErr.Reset)
+		}
+		out.Err = fetchErr
+		err := e.Repos.RecordEnrichment(ctx, store.RepoEnrichment{
+			RepoName: name, Provider: out.Host, CheckedAt: now, Error: fetchErr.Error(),
+		})
+		return out, err
+	}
+	if res.RateLimit.Remaining == 0 {
+		e.limit(out.Host, res.RateLimit.Reset)
+	}
+
+	err = db.InTx(ctx, e.Repos.DB, func(tx *sql.Tx) error {
+		repos := store.NewReposStore(tx)
+		// Forges ignore case in paths, so only a real rename is a move.
+		if res.URL != "" && !strings.EqualFold(git.CanonicalURL(res.URL), git.CanonicalURL(repo.URL)) {
+			if err := repos.Move(ctx, name, res.URL); err != nil {
+				return err
+			}
+			out.MovedTo = res.URL
+		}
+		if !res.NotModified {
+			if err := repos.UpdateMetadata(ctx, name, res.Metadata); err != nil {
+				return err
+			}
+		}
+		return repos.RecordEnrichment(ctx, store.RepoEnrichment{
+			RepoName: name, Provider: out.Host, ETag: res.ETag, EnrichedAt: now, CheckedAt: now,
+		})
+	})
+	if err != nil {
+		return out, err
+	}
+	out.Updated = !res.NotModified
+	out.NotModified = res.NotModified
+	return out, nil
+}
+
+// limit marks host as rate limited until reset, or for a minute if the forge
+// did not say.
+func (e *Enricher) limit(host string, reset time.Time) {
+	if reset.IsZero() {
+		reset = time.Now().Add(time.Minute)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.limited == nil {
+		e.limited = make(map[string]time.Time)
+	}
+	e.limited[host] = reset
+}
+
+// waitForLimit returns nil once host may be queried, sleeping up to MaxWait,
+// or a *RateLimitError if it stays limited for longer.
+func (e *Enricher) waitForLimit(ctx context.Context, host string) error {
+	e.mu.Lock()
+	reset, ok := e.limited[host]
+	e.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	wait := time.Until(reset)
+	if wait > e.MaxWait {
+		return &RateLimitError{Reset: reset}
+	}
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	e.mu.Lock()
+	if e.limited[host].Equal(reset) {
+		delete(e.limited, host)
+	}
+	e.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package enrich
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/yourorg/arc-sdk/store"
+)
+
+// DefaultGiteaURL is Codeberg, the largest public Gitea (Forgejo) instance.
+const DefaultGiteaURL = "https://codeberg.org"
+
+// Gitea fetches metadata from the Gitea REST API (v1), which Forgejo also
+// serves.
+type Gitea struct {
+	c *forgeClient
+}
+
+// NewGitea creates a Gitea provider. BaseURL is the instance root, e.g.
+// https://gitea.example.com; it defaults to DefaultGiteaURL.
+func NewGitea(opts ProviderOptions) *Gitea {
+	c := newForgeClient(opts, DefaultGiteaURL)
+	if opts.Token != "" {
+		c.auth = func(req *http.Request) {
+			req.Header.Set("Authorization", "token "+opts.Token)
+		}
+	}
+	return &Gitea{c: c}
+}
+
+type giteaRepo struct {
+	HTMLURL       string   `json:"html_url"`
+	Description   string   `json:"description"`
+	Language      string   `json:"language"`
+	Topics        []string `json:"topics"`
+	Stars         int      `json:"stars_count"`
+	Forks         int      `json:"forks_count"`
+	Website       string   `json:"website"`
+	DefaultBranch string   `json:"default_branch"`
+	Archived      bool     `json:"archived"`
+	// Licenses is reported by Gitea 1.22 and later.
+	Licenses []string `json:"licenses"`
+}
+
+// Fetch implements MetadataProvider.
+func (g *Gitea) Fetch(ctx context.Context, owner, repo, etag string) (*Result, error) {
+	var r giteaRepo
+	res, err := g.c.get(ctx, "/api/v1/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(repo), etag, &r)
+	if err != nil || res.NotModified {
+		return res, err
+	}
+	res.Metadata = store.RepoMetadata{
+		Description:   r.Description,
+		Language:      r.Language,
+		Topics:        r.Topics,
+		Stars:         r.Stars,
+		Forks:         r.Forks,
+		Homepage:      r.Website,
+		DefaultBranch: r.DefaultBranch,
+		Archived:      r.Archived,
+	}
+	res.URL = r.HTMLURL
+	if len(r.Licenses) > 0 {
+		res.Metadata.License = r.Licenses[0]
+	}
+	return res, nil
+}
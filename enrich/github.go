@@ -0,0 +1,78 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package enrich
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/yourorg/arc-sdk/store"
+)
+
+// DefaultGitHubURL is the GitHub REST API root.
+const DefaultGitHubURL = "https://api.github.com"
+
+// GitHub fetches metadata from the GitHub REST API.
+type GitHub struct {
+	c *forgeClient
+}
+
+// NewGitHub creates a GitHub provider. BaseURL is the API root, e.g.
+// https://ghe.example.com/api/v3 for GitHub Enterprise; it defaults to
+// DefaultGitHubURL.
+func NewGitHub(opts ProviderOptions) *GitHub {
+	c := newForgeClient(opts, DefaultGitHubURL)
+	c.accept = "application/vnd.github+json"
+	if opts.Token != "" {
+		c.auth = func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+opts.Token)
+		}
+	}
+	return &GitHub{c: c}
+}
+
+type githubRepo struct {
+	HTMLURL       string   `json:"html_url"`
+	Description   string   `json:"description"`
+	Language      string   `json:"language"`
+	Topics        []string `json:"topics"`
+	Stars         int      `json:"stargazers_count"`
+	Forks         int      `json:"forks_count"`
+	Homepage      string   `json:"homepage"`
+	DefaultBranch string   `json:"default_branch"`
+	Archived      bool     `json:"archived"`
+	License       *struct {
+		SPDXID string `json:"spdx_id"`
+		Name   string `json:"name"`
+	} `json:"license"`
+}
+
+// Fetch implements MetadataProvider.
+func (g *GitHub) Fetch(ctx context.Context, owner, repo, etag string) (*Result, error) {
+	var r githubRepo
+	res, err := g.c.get(ctx, "/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(repo), etag, &r)
+	if err != nil || res.NotModified {
+		return res, err
+	}
+	res.Metadata = store.RepoMetadata{
+		Description:   r.Description,
+		Language:      r.Language,
+		Topics:        r.Topics,
+		Stars:         r.Stars,
+		Forks:         r.Forks,
+		Homepage:      r.Homepage,
+		DefaultBranch: r.DefaultBranch,
+		Archived:      r.Archived,
+	}
+	res.URL = r.HTMLURL
+	if r.License != nil {
+		// GitHub reports unrecognised licenses as NOASSERTION.
+		res.Metadata.License = r.License.SPDXID
+		if res.Metadata.License == "" || res.Metadata.License == "NOASSERTION" {
+			res.Metadata.License = r.License.Name
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package enrich
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/yourorg/arc-sdk/store"
+)
+
+// DefaultGitLabURL is the gitlab.com instance root.
+const DefaultGitLabURL = "https://gitlab.com"
+
+// GitLab fetches metadata from the GitLab REST API (v4).
+type GitLab struct {
+	c *forgeClient
+}
+
+// NewGitLab creates a GitLab provider. BaseURL is the instance root, e.g.
+// https://gitlab.example.com; it defaults to DefaultGitLabURL.
+func NewGitLab(opts ProviderOptions) *GitLab {
+	c := newForgeClient(opts, DefaultGitLabURL)
+	if opts.Token != "" {
+		c.auth = func(req *http.Request) {
+			req.Header.Set("PRIVATE-TOKEN", opts.Token)
+		}
+	}
+	return &GitLab{c: c}
+}
+
+type gitlabProject struct {
+	WebURL        string   `json:"web_url"`
+	Description   string   `json:"description"`
+	Topics        []string `json:"topics"`
+	TagList       []string `json:"tag_list"`
+	Stars         int      `json:"star_count"`
+	Forks         int      `json:"forks_count"`
+	DefaultBranch string   `json:"default_branch"`
+	Archived      bool     `json:"archived"`
+	License       *struct {
+		Nickname string `json:"nickname"`
+		Name     string `json:"name"`
+	} `json:"license"`
+}
+
+// Fetch implements MetadataProvider. GitLab has no homepage field, and the
+// primary language takes a second request, made only when the project
+// changed.
+func (g *GitLab) Fetch(ctx context.Context, owner, repo, etag string) (*Result, error) {
+	var p gitlabProject
+	path := "/api/v4/projects/" + url.PathEscape(owner+"/"+repo)
+	res, err := g.c.get(ctx, path+"?license=true", etag, &p)
+	if err != nil || res.NotModified {
+		return res, err
+	}
+	res.Metadata = store.RepoMetadata{
+		Description:   p.Description,
+		Topics:        p.Topics,
+		Stars:         p.Stars,
+		Forks:         p.Forks,
+		DefaultBranch: p.DefaultBranch,
+		Archived:      p.Archived,
+	}
+	res.URL = p.WebURL
+	if res.Metadata.Topics == nil {
+		// Instances before 14.5 only have the deprecated tag_list.
+		res.Metadata.Topics = p.TagList
+	}
+	if p.License != nil {
+		res.Metadata.License = p.License.Nickname
+		if res.Metadata.License == "" {
+			res.Metadata.License = p.License.Name
+		}
+	}
+
+	// Languages are percentages by name; the largest is the primary one.
+	var langs map[string]float64
+	langRes, err := g.c.get(ctx, path+"/languages", "", &langs)
+	if err != nil {
+		return nil, err
+	}
+	res.RateLimit = langRes.RateLimit
+	var best float64
+	for lang, pct := range langs {
+		if pct > best || pct == best && lang < res.Metadata.Language {
+			res.Metadata.Language, best = lang, pct
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// forgeClient makes the JSON GET requests shared by the providers.
+type forgeClient struct {
+	base   string
+	client *http.Client
+	// auth adds credentials to a request; nil for anonymous access.
+	auth func(*http.Request)
+	// accept is the Accept header to send.
+	accept string
+}
+
+func newForgeClient(opts ProviderOptions, defaultBase string) *forgeClient {
+	base := opts.BaseURL
+	if base == "" {
+		base = defaultBase
+	}
+	return &forgeClient{
+		base:   strings.TrimSuffix(base, "/"),
+		client: opts.client(),
+		accept: "application/json",
+	}
+}
+
+// get fetches base+path and decodes the JSON body into out. With a non-empty
+// etag it makes a conditional request; a 304 reply leaves out untouched and
+// sets NotModified on the returned Result.
+func (c *forgeClient) get(ctx context.Context, path, etag string, out any) (*Result, error) {
+	url := c.base + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", c.accept)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if c.auth != nil {
+		c.auth(req)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	res := &Result{ETag: resp.Header.Get("ETag"), RateLimit: parseRateLimit(resp.Header)}
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		res.NotModified = true
+		if res.ETag == "" {
+			res.ETag = etag
+		}
+		return res, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("GET %s: %w", url, ErrNotFound)
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && (res.RateLimit.Remaining == 0 || resp.Header.Get("Retry-After") != ""):
+		reset := retryAfter(resp.Header)
+		if reset.IsZero() {
+			reset = res.RateLimit.Reset
+		}
+		return nil, &RateLimitError{Reset: reset}
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("GET %s: %s: %s", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", url, err)
+	}
+	return res, nil
+}
+
+// parseRateLimit reads GitHub/Gitea style X-RateLimit-* or GitLab style
+// RateLimit-* headers.
+func parseRateLimit(h http.Header) RateLimit {
+	rl := RateLimit{Remaining: -1}
+	if v := firstHeader(h, "X-RateLimit-Remaining", "RateLimit-Remaining"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			rl.Remaining = n
+		}
+	}
+	if v := firstHeader(h, "X-RateLimit-Reset", "RateLimit-Reset"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			// Forges send Unix seconds; small values are delays in seconds.
+			if n < 1_000_000_000 {
+				rl.Reset = time.Now().Add(time.Duration(n) * time.Second)
+			} else {
+				rl.Reset = time.Unix(n, 0)
+			}
+		}
+	}
+	return rl
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(h http.Header) time.Time {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return time.Time{}
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return time.Now().Add(time.Duration(n) * time.Second)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+func firstHeader(h http.Header, keys ...string) string {
+	for _, k := range keys {
+		if v := h.Get(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
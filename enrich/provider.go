@@ -0,0 +1,89 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+// Package enrich fills repository metadata (stars, topics, license, ...)
+// from forge APIs. A MetadataProvider talks to one forge; an Enricher routes
+// repos to providers by host and writes the results through store.ReposStore.
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/yourorg/arc-sdk/store"
+)
+
+var (
+	// ErrNotFound is returned when the forge does not know the repository,
+	// or hides it from the token in use.
+	ErrNotFound = errors.New("repository not found on forge")
+	// ErrRateLimited is matched by every *RateLimitError.
+	ErrRateLimited = errors.New("forge rate limit exceeded")
+	// ErrNoProvider is reported for repos whose host has no provider.
+	ErrNoProvider = errors.New("no metadata provider for host")
+)
+
+// MetadataProvider fetches repository metadata from one forge.
+type MetadataProvider interface {
+	// Fetch returns metadata for owner/repo. Given the ETag of an earlier
+	// Result, it returns a Result with NotModified set if nothing changed.
+	Fetch(ctx context.Context, owner, repo, etag string) (*Result, error)
+}
+
+// Result is the outcome of a successful Fetch.
+type Result struct {
+	// Metadata and URL are zero when NotModified is set.
+	Metadata store.RepoMetadata
+	// URL is the repo's web URL as the forge reports it, which differs from
+	// the requested one after a rename or transfer.
+	URL         string
+	ETag        string
+	NotModified bool
+	RateLimit   RateLimit
+}
+
+// RateLimit is the forge's request budget as of a response.
+type RateLimit struct {
+	// Remaining is the number of requests left, or -1 if not reported.
+	Remaining int
+	// Reset is when the budget refills; zero if not reported.
+	Reset time.Time
+}
+
+// RateLimitError is returned when a forge refuses a request for exceeding
+// its rate limit.
+type RateLimitError struct {
+	// Reset is when requests may resume.
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("forge rate limit exceeded until %s", e.Reset.Format(time.RFC3339))
+}
+
+// Is reports whether target is ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// ProviderOptions configures a provider.
+type ProviderOptions struct {
+	// BaseURL overrides the forge's default root URL (see each constructor),
+	// e.g. for a self-hosted instance or a test server.
+	BaseURL string
+	// Token authenticates requests; empty makes anonymous requests, which
+	// forges rate-limit harder.
+	Token string
+	// Client defaults to an http.Client with a 30s timeout.
+	Client *http.Client
+}
+
+func (o ProviderOptions) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Inspection is what Inspect learns about a clone without network access.
+type Inspection struct {
+	DefaultBranch string
+	// CommitCount and LastCommitAt are zero for a repo without commits.
+	// In a shallow clone CommitCount only counts the fetched history.
+	CommitCount  int
+	LastCommitAt int64
+	Shallow      bool
+
+	// Language is the language with the most bytes in Languages, which maps
+	// language names to the bytes of tracked source files.
+	Language  string
+	Languages map[string]int64
+	// License is the SPDX identifier of the license file, if recognised.
+	License string
+	// Description is the README's first paragraph as plain text.
+	Description string
+}
+
+// Inspect examines the clone at repoPath.
+func Inspect(ctx context.Context, repoPath string) (*Inspection, error) {
+	// Ask git rather than look for a .git directory, so worktrees and
+	// submodules, whose .git is a file, are accepted too. The prefix is
+	// empty only at the top of a work tree.
+	if out, err := exec.CommandContext(ctx, "git", "-C", repoPath, "rev-parse", "--show-prefix").Output(); err != nil || strings.TrimSpace(string(out)) != "" {
+		return nil, fmt.Errorf("inspect %s: not a git repository", repoPath)
+	}
+	var in Inspection
+	var err error
+
+	in.DefaultBranch, err = remoteDefaultBranch(ctx, repoPath)
+	if err != nil {
+		if in.DefaultBranch, err = GetDefaultBranch(ctx, repoPath); err != nil {
+			return nil, err
+		}
+	}
+	if in.Shallow, err = IsShallow(ctx, repoPath); err != nil {
+		return nil, err
+	}
+
+	// A fresh repo has an unborn HEAD and nothing to count.
+	if exec.CommandContext(ctx, "git", "-C", repoPath, "rev-parse", "--verify", "--quiet", "HEAD").Run() == nil {
+		if in.CommitCount, err = GetCommitCount(ctx, repoPath); err != nil {
+			return nil, err
+		}
+		if in.LastCommitAt, err = GetLastCommitTime(ctx, repoPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if in.Languages, err = DetectLanguages(ctx, repoPath); err != nil {
+		return nil, err
+	}
+	in.Language = primaryLanguage(in.Languages)
+
+	if text, ok := readRootFile(repoPath, "license", "licence", "copying", "unlicense"); ok {
+		in.License = DetectLicense(text)
+	}
+	if text, ok := readRootFile(repoPath, "readme"); ok {
+		in.Description = ReadmeDescription(text)
+	}
+	return &in, nil
+}
+
+// remoteDefaultBranch returns the branch origin/HEAD points at, which is
+// only set for clones.
+func remoteDefaultBranch(ctx context.Context, repoPath string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "-C", repoPath, "symbolic-ref", "--short", "refs/remotes/origin/HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("get origin HEAD: %w", err)
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "origin/"), nil
+}
+
+// readRootFile reads the first regular file in repoPath whose name, minus
+// any extension, is one of names (compared case-insensitively).
+func readRootFile(repoPath string, names ...string) (string, bool) {
+	entries, err := os.ReadDir(repoPath)
+	if err != nil {
+		return "", false
+	}
+	for _, name := range names {
+		for _, e := range entries {
+			base := strings.ToLower(e.Name())
+			base = strings.TrimSuffix(base, filepath.Ext(base))
+			if base != name || !e.Type().IsRegular() {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(repoPath, e.Name()))
+			if err != nil {
+				continue
+			}
+			return string(data), true
+		}
+	}
+	return "", false
+}
+
+// maxDescription caps the length of a README-derived description.
+const maxDescription = 300
+
+var (
+	mdImage     = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	mdLink      = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	htmlTag     = regexp.MustCompile(`<[^>]+>`)
+	mdEmphasis  = regexp.MustCompile("(\\*\\*|__|`)")
+	setextUnder = regexp.MustCompile(`^(=+|-+)$`)
+)
+
+// ReadmeDescription returns the first paragraph of prose in a Markdown,
+// reStructuredText or plain README, skipping headings, badges and HTML, with
+// inline markup removed.
+func ReadmeDescription(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var para []string
+	inFence := false
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		next := ""
+		if i+1 < len(lines) {
+			next = strings.TrimSpace(lines[i+1])
+		}
+		skip := line == "" ||
+			strings.HasPrefix(line, "#") ||
+			strings.HasPrefix(line, "..") ||
+			setextUnder.MatchString(line) ||
+			setextUnder.MatchString(next) && len(para) == 0 ||
+			strings.TrimSpace(htmlTag.ReplaceAllString(mdImage.ReplaceAllString(line, ""), "")) == "" ||
+			strings.HasPrefix(line, "[![")
+		if skip {
+			if len(para) > 0 {
+				break
+			}
+			continue
+		}
+		para = append(para, line)
+	}
+
+	desc := strings.Join(para, " ")
+	desc = mdImage.ReplaceAllString(desc, "")
+	desc = mdLink.ReplaceAllString(desc, "$1")
+	desc = htmlTag.ReplaceAllString(desc, "")
+	desc = mdEmphasis.ReplaceAllString(desc, "")
+	desc = strings.Join(strings.Fields(desc), " ")
+	if len(desc) > maxDescription {
+		cut := strings.LastIndex(desc[:maxDescription], " ")
+		if cut <= 0 {
+			// No space to break at: back up to the start of a character.
+			cut = maxDescription
+			for cut > 0 && !utf8.RuneStart(desc[cut]) {
+				cut--
+			}
+		}
+		desc = strings.TrimRight(desc[:cut], " ,;:") + "…"
+	}
+	return desc
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const mitText = `MIT License
+
+Copyright (c) 2024 Someone
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction.
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+`
+
+func TestDetectLicense(t *testing.T) {
+	tests := map[string]string{
+		mitText: "MIT",
+		"                                 Apache License\n                           Version 2.0, January 2004\n":     "Apache-2.0",
+		"GNU GENERAL PUBLIC LICENSE\n Version 3, 29 June 2007\n... use the GNU Lesser General Public License instead": "GPL-3.0",
+		"GNU LESSER GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007\n... the GNU General Public License":              "LGPL-3.0",
+		"Redistribution and use in source and binary forms, with or without\nmodification, are permitted ...":         "BSD-2-Clause",
+		"Redistribution and use in source and binary forms, with or without modification, are permitted. " +
+			"3. Neither the name of the copyright holder ...": "BSD-3-Clause",
+		"All rights reserved.": "",
+	}
+	for text, want := range tests {
+		if got := DetectLicense(text); got != want {
+			t.Errorf("DetectLicense(%.40q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestReadmeDescription(t *testing.T) {
+	tests := map[string]string{
+		"# Tool\n\n[![CI](https://x/badge.svg)](https://x)\n\nA **fast** tool for\n[parsing](https://p) `things`.\n\nMore.": "A fast tool for parsing things.",
+		"Tool\n====\n\n<p align=\"center\"><img src=\"logo.png\"></p>\n\nDoes stuff.\n":                                     "Does stuff.",
+		".. image:: badge.svg\n\nProject\n-------\n\nRST readme here.\n":                                                    "RST readme here.",
+		"```\ncode\n```\n": "",
+	}
+	for text, want := range tests {
+		if got := ReadmeDescription(text); got != want {
+			t.Errorf("ReadmeDescription(%.30q) = %q, want %q", text, got, want)
+		}
+	}
+	long := strings.Repeat("word ", 100)
+	if got := ReadmeDescription(long); len(got) > maxDescription+len("…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("long description not truncated: %q", got)
+	}
+	if got := ReadmeDescription("x" + strings.Repeat("é", 200)); !utf8.ValidString(got) || !strings.HasSuffix(got, "…") {
+		t.Errorf("unbroken description cut mid-character: %q", got)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	src := filepath.Join(t.TempDir(), "src")
+	files := map[string]string{
+		"main.go":            strings.Repeat("package main\n", 20),
+		"util.go":            "package main\n",
+		"scripts/build.py":   "print('hi')\n",
+		"vendor/big/big.c":   strings.Repeat("int x;\n", 1000),
+		"web/app.min.js":     strings.Repeat("x", 5000),
+		"LICENSE":            mitText,
+		"README.md":          "# src\n\nInspects things.\n",
+		"docs/guide.md":      strings.Repeat("prose ", 1000),
+		"testdata/ignored.c": "",
+	}
+	for name, body := range files {
+		path := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q", "-b", "trunk", src)
+	run("-C", src, "add", ".")
+	run("-C", src, "commit", "-q", "-m", "first")
+	run("-C", src, "commit", "-q", "--allow-empty", "-m", "second")
+	clone := filepath.Join(t.TempDir(), "clone")
+	run("clone", "-q", src, clone)
+	run("-C", clone, "checkout", "-q", "-b", "feature")
+
+	in, err := Inspect(ctx, clone)
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if in.DefaultBranch != "trunk" || in.CommitCount != 2 || in.LastCommitAt == 0 || in.Shallow {
+		t.Fatalf("git facts = %+v", in)
+	}
+	if in.Language != "Go" || in.Languages["Go"] != 20*13+13 || in.Languages["Python"] == 0 || in.Languages["JavaScript"] != 0 {
+		t.Fatalf("languages = %v, primary %q", in.Languages, in.Language)
+	}
+	if in.License != "MIT" || in.Description != "Inspects things." {
+		t.Fatalf("license %q, description %q", in.License, in.Description)
+	}
+
+	// A worktree's .git is a file.
+	wt := filepath.Join(t.TempDir(), "wt")
+	run("-C", src, "worktree", "add", "-q", "-b", "wt", wt)
+	if in, err := Inspect(ctx, wt); err != nil || in.CommitCount != 2 {
+		t.Fatalf("Inspect worktree = %+v, %v", in, err)
+	}
+
+	if _, err := Inspect(ctx, t.TempDir()); err == nil {
+		t.Fatal("Inspect of a plain directory succeeded")
+	}
+	if _, err := Inspect(ctx, filepath.Join(src, "scripts")); err == nil {
+		t.Fatal("Inspect of a repo subdirectory succeeded")
+	}
+}
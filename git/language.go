@@ -0,0 +1,154 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// extLanguages maps lower-case file extensions to language names as GitHub
+// spells them. Prose and data formats (Markdown, JSON, YAML) are left out so
+// they never win.
+var extLanguages = map[string]string{
+	".go":     "Go",
+	".py":     "Python",
+	".pyi":    "Python",
+	".js":     "JavaScript",
+	".mjs":    "JavaScript",
+	".cjs":    "JavaScript",
+	".jsx":    "JavaScript",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".rs":     "Rust",
+	".c":      "C",
+	".h":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".cxx":    "C++",
+	".hh":     "C++",
+	".hpp":    "C++",
+	".cs":     "C#",
+	".java":   "Java",
+	".kt":     "Kotlin",
+	".kts":    "Kotlin",
+	".scala":  "Scala",
+	".swift":  "Swift",
+	".m":      "Objective-C",
+	".mm":     "Objective-C++",
+	".rb":     "Ruby",
+	".php":    "PHP",
+	".pl":     "Perl",
+	".pm":     "Perl",
+	".lua":    "Lua",
+	".sh":     "Shell",
+	".bash":   "Shell",
+	".zsh":    "Shell",
+	".fish":   "Fish",
+	".ps1":    "PowerShell",
+	".hs":     "Haskell",
+	".ml":     "OCaml",
+	".mli":    "OCaml",
+	".ex":     "Elixir",
+	".exs":    "Elixir",
+	".erl":    "Erlang",
+	".clj":    "Clojure",
+	".cljs":   "Clojure",
+	".el":     "Emacs Lisp",
+	".lisp":   "Common Lisp",
+	".scm":    "Scheme",
+	".rkt":    "Racket",
+	".fs":     "F#",
+	".dart":   "Dart",
+	".r":      "R",
+	".jl":     "Julia",
+	".zig":    "Zig",
+	".nim":    "Nim",
+	".cr":     "Crystal",
+	".elm":    "Elm",
+	".vue":    "Vue",
+	".svelte": "Svelte",
+	".html":   "HTML",
+	".htm":    "HTML",
+	".css":    "CSS",
+	".scss":   "SCSS",
+	".sql":    "SQL",
+	".tf":     "HCL",
+	".nix":    "Nix",
+	".vim":    "Vim Script",
+	".ipynb":  "Jupyter Notebook",
+	".sol":    "Solidity",
+	".groovy": "Groovy",
+	".asm":    "Assembly",
+	".s":      "Assembly",
+}
+
+// skippedDirs are path components whose contents are third-party or
+// generated and so say little about the repo's own language.
+var skippedDirs = map[string]bool{
+	"vendor":       true,
+	"node_modules": true,
+	"third_party":  true,
+	"thirdparty":   true,
+	"dist":         true,
+}
+
+// DetectLanguages sums the sizes of tracked files in repoPath by language,
+// judged by extension. Vendored, minified and unrecognised files are skipped.
+func DetectLanguages(ctx context.Context, repoPath string) (map[string]int64, error) {
+	out, err := exec.CommandContext(ctx, "git", "-C", repoPath, "ls-files", "-z").Output()
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+
+	langs := make(map[string]int64)
+	for _, name := range bytes.Split(out, []byte{0}) {
+		path := string(name)
+		if path == "" || vendored(path) {
+			continue
+		}
+		lang, ok := extLanguages[strings.ToLower(filepath.Ext(path))]
+		if !ok {
+			continue
+		}
+		// Lstat so symlinks count as nothing rather than as their target.
+		info, err := os.Lstat(filepath.Join(repoPath, path))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		langs[lang] += info.Size()
+	}
+	return langs, nil
+}
+
+func vendored(path string) bool {
+	if strings.Contains(path, ".min.") {
+		return true
+	}
+	dirs := strings.Split(path, "/")
+	for _, dir := range dirs[:len(dirs)-1] {
+		if skippedDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// primaryLanguage returns the language with the most bytes, breaking ties by
+// name.
+func primaryLanguage(langs map[string]int64) string {
+	var best string
+	var most int64
+	for lang, n := range langs {
+		if n > most || n == most && lang < best {
+			best, most = lang, n
+		}
+	}
+	return best
+}
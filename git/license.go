@@ -0,0 +1,91 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package git
+
+import (
+	"strings"
+	"unicode"
+)
+
+// licenseTemplate identifies an SPDX license by phrases its text always
+// contains. Phrases are in normalizeLicense form.
+type licenseTemplate struct {
+	id       string
+	phrases  []string
+	excludes []string
+}
+
+// licenseTemplates is ordered so that licenses whose text contains another's
+// phrases come first. The GNU licenses mention each other, so they are told
+// apart by their title and date lines.
+var licenseTemplates = []licenseTemplate{
+	{id: "AGPL-3.0", phrases: []string{"gnu affero general public license version 3 19 november 2007"}},
+	{id: "LGPL-3.0", phrases: []string{"gnu lesser general public license version 3 29 june 2007"}},
+	{id: "LGPL-2.1", phrases: []string{"gnu lesser general public license version 2 1 february 1999"}},
+	{id: "GPL-3.0", phrases: []string{"gnu general public license version 3 29 june 2007"}},
+	{id: "GPL-2.0", phrases: []string{"gnu general public license version 2 june 1991"}},
+	{id: "Apache-2.0", phrases: []string{"apache license", "version 2 0"}},
+	{id: "MPL-2.0", phrases: []string{"mozilla public license version 2 0"}},
+	{id: "EPL-2.0", phrases: []string{"eclipse public license v 2 0"}},
+	{id: "BSL-1.0", phrases: []string{"boost software license version 1 0"}},
+	{id: "CC0-1.0", phrases: []string{"cc0 1 0 universal"}},
+	{id: "Unlicense", phrases: []string{"this is free and unencumbered software released into the public domain"}},
+	{id: "MIT", phrases: []string{
+		"permission is hereby granted free of charge to any person obtaining a copy",
+		"the above copyright notice and this permission notice shall be included",
+	}},
+	{id: "ISC", phrases: []string{
+		"permission to use copy modify and or distribute this software for any purpose with or without fee is hereby granted",
+	}},
+	{id: "BSD-3-Clause", phrases: []string{
+		"redistribution and use in source and binary forms with or without modification are permitted",
+		"neither the name of",
+	}},
+	{id: "BSD-2-Clause", phrases: []string{
+		"redistribution and use in source and binary forms with or without modification are permitted",
+	}, excludes: []string{"neither the name of"}},
+	{id: "Zlib", phrases: []string{
+		"this software is provided as is without any express or implied warranty",
+		"altered source versions must be plainly marked as such",
+	}},
+}
+
+// DetectLicense returns the SPDX identifier of the license in text, or ""
+// if it matches none of the common templates.
+func DetectLicense(text string) string {
+	norm := normalizeLicense(text)
+	for _, t := range licenseTemplates {
+		if containsAll(norm, t.phrases) && !containsAny(norm, t.excludes) {
+			return t.id
+		}
+	}
+	return ""
+}
+
+// normalizeLicense lower-cases text and reduces every run of punctuation and
+// whitespace to one space, so that wrapping and quoting styles don't matter.
+func normalizeLicense(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(fields, " ") + " "
+}
+
+func containsAll(s string, phrases []string) bool {
+	for _, p := range phrases {
+		if !strings.Contains(s, " "+p+" ") {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(s string, phrases []string) bool {
+	for _, p := range phrases {
+		if strings.Contains(s, " "+p+" ") {
+			return true
+		}
+	}
+	return false
+}
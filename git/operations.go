@@ -6,42 +6,63 @@ package git
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
-// Clone clones a repository to the given path.
-func Clone(ctx context.Context, url, destPath string) error {
-	// Ensure parent directory exists
-	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
-		return fmt.Errorf("create parent dir: %w", err)
-	}
+// RunOptions controls how a git command runs. Nil writers discard output.
+type RunOptions struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	// Env holds extra KEY=value variables for the command's environment.
+	Env []string
+}
 
-	cmd := exec.CommandContext(ctx, "git", "clone", url, destPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// stdOptions streams output to the terminal.
+func stdOptions() RunOptions {
+	return RunOptions{Stdout: os.Stdout, Stderr: os.Stderr}
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("git clone: %w", err)
+func (o RunOptions) command(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdout = o.Stdout
+	cmd.Stderr = o.Stderr
+	if len(o.Env) > 0 {
+		cmd.Env = append(os.Environ(), o.Env...)
 	}
+	return cmd
+}
 
-	return nil
+// Clone clones a repository to the given path.
+func Clone(ctx context.Context, url, destPath string) error {
+	return CloneWithOptions(ctx, url, destPath, 0, stdOptions())
 }
 
 // CloneShallow clones a repository with depth=1.
 func CloneShallow(ctx context.Context, url, destPath string) error {
+	return CloneWithOptions(ctx, url, destPath, 1, stdOptions())
+}
+
+// CloneWithOptions clones a repository to the given path, truncating history
+// to depth commits if depth > 0.
+func CloneWithOptions(ctx context.Context, url, destPath string, depth int, opts RunOptions) error {
+	// Ensure parent directory exists
 	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
 		return fmt.Errorf("create parent dir: %w", err)
 	}
 
-	cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", url, destPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	args := []string{"clone"}
+	if depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(depth))
+	}
+	cmd := opts.command(ctx, append(args, url, destPath)...)
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("git clone --depth 1: %w", err)
+		return fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
 	}
 
 	return nil
@@ -49,9 +70,12 @@ func CloneShallow(ctx context.Context, url, destPath string) error {
 
 // Pull performs a git pull in the given directory.
 func Pull(ctx context.Context, repoPath string) error {
-	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "pull")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	return PullWithOptions(ctx, repoPath, stdOptions())
+}
+
+// PullWithOptions performs a git pull in the given directory.
+func PullWithOptions(ctx context.Context, repoPath string, opts RunOptions) error {
+	cmd := opts.command(ctx, "-C", repoPath, "pull")
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("git pull: %w", err)
@@ -62,9 +86,12 @@ func Pull(ctx context.Context, repoPath string) error {
 
 // Fetch performs a git fetch in the given directory.
 func Fetch(ctx context.Context, repoPath string) error {
-	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "fetch")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	return FetchWithOptions(ctx, repoPath, stdOptions())
+}
+
+// FetchWithOptions performs a git fetch in the given directory.
+func FetchWithOptions(ctx context.Context, repoPath string, opts RunOptions) error {
+	cmd := opts.command(ctx, "-C", repoPath, "fetch")
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("git fetch: %w", err)
@@ -113,6 +140,17 @@ func GetRemoteURL(ctx context.Context, repoPath string) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// IsShallow reports whether the repository at repoPath is a shallow clone.
+// Git answers for worktrees and .git files too.
+func IsShallow(ctx context.Context, repoPath string) (bool, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "rev-parse", "--is-shallow-repository")
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("check shallow: %w", err)
+	}
+	return strings.TrimSpace(string(output)) == "true", nil
+}
+
 // GetCommitCount returns the number of commits in a repository.
 func GetCommitCount(ctx context.Context, repoPath string) (int, error) {
 	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "rev-list", "--count", "HEAD")
@@ -25,6 +25,9 @@ var (
 
 	// httpURLRegex matches https://host/owner/repo or https://host/owner/repo.git
 	httpURLRegex = regexp.MustCompile(`^https?://([^/]+)/([^/]+)/([^/.]+?)(?:\.git)?/?$`)
+
+	// scpURLRegex matches scp-style [user@]host:path
+	scpURLRegex = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
 )
 
 // ParseGitURL parses a git URL and extracts platform, owner, and repo name.
@@ -79,6 +82,31 @@ func ParseGitURL(rawURL string) (*ParsedURL, error) {
 	}, nil
 }
 
+// CanonicalURL returns the form of a repository URL shared by every way of
+// writing it: https scheme, lower-case host without "www." or port, no user,
+// and no ".git" suffix or trailing slashes. So git@github.com:x/y.git and
+// https://www.GitHub.com/x/y/ both become https://github.com/x/y. Path case
+// is kept. Strings that are not remote URLs are only trimmed.
+func CanonicalURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	var host, path string
+	if m := scpURLRegex.FindStringSubmatch(rawURL); m != nil && !strings.Contains(rawURL, "://") {
+		host, path = m[1], m[2]
+	} else if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+	} else {
+		return rawURL
+	}
+
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	path = strings.TrimRight(path, "/")
+	if path == "" {
+		return "https://" + host
+	}
+	return "https://" + host + "/" + path
+}
+
 func detectPlatform(host string) string {
 	host = strings.ToLower(host)
 	switch {
@@ -0,0 +1,23 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package git
+
+import "testing"
+
+func TestCanonicalURL(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:x/y.git":               "https://github.com/x/y",
+		"https://github.com/x/y":               "https://github.com/x/y",
+		"https://www.GitHub.com/x/y/":          "https://github.com/x/y",
+		"http://github.com/x/y.git/":           "https://github.com/x/y",
+		"ssh://git@gitlab.com:22/g/sub/proj":   "https://gitlab.com/g/sub/proj",
+		"https://user@codeberg.org/Forgejo/X/": "https://codeberg.org/Forgejo/X",
+		" not a url ":                          "not a url",
+	}
+	for in, want := range tests {
+		if got := CanonicalURL(in); got != want {
+			t.Errorf("CanonicalURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
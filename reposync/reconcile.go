@@ -0,0 +1,354 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package reposync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourorg/arc-sdk/config"
+	"github.com/yourorg/arc-sdk/db"
+	"github.com/yourorg/arc-sdk/git"
+	"github.com/yourorg/arc-sdk/store"
+)
+
+// IssueKind classifies a difference between the store and the clones on disk.
+type IssueKind string
+
+const (
+	// IssueMissing is a repo marked cloned whose clone is gone. The fix
+	// marks it not cloned.
+	IssueMissing IssueKind = "missing"
+	// IssueOrphan is a clone under Root that matches no repo. The fix adds
+	// a repo for it from its origin remote.
+	IssueOrphan IssueKind = "orphan"
+	// IssuePathMismatch is a repo whose clone is somewhere other than its
+	// recorded ClonePath, or that is not marked cloned at all. The fix
+	// records the clone's path.
+	IssuePathMismatch IssueKind = "path-mismatch"
+	// IssueShallow is a clone that is shallow when the store says full, or
+	// the reverse. The fix re-reads the clone with UpdateFromClone.
+	IssueShallow IssueKind = "shallow"
+	// IssueDuplicateClone is a second clone of a repo that already has one.
+	// It is only reported; deleting a clone is left to the user.
+	IssueDuplicateClone IssueKind = "duplicate-clone"
+)
+
+// Issue is one difference found by Reconcile.
+type Issue struct {
+	Kind IssueKind
+	// Repo is the stored repo concerned; empty for orphans and their
+	// duplicates.
+	Repo string
+	// Path is the clone directory concerned, or for IssueMissing the
+	// recorded path that no longer holds one.
+	Path string
+	// URL is an orphan's origin remote.
+	URL    string
+	Detail string
+	// Fixed is set once the fix has been applied; Err is why it failed.
+	Fixed bool
+	Err   error
+}
+
+// ReconcileOptions configures Reconcile.
+type ReconcileOptions struct {
+	// Root is the directory searched for clones.
+	Root string
+	// MaxDepth bounds how far below Root clones are looked for; default 3,
+	// enough for Root/host/owner/repo.
+	MaxDepth int
+	// DryRun reports the issues without fixing any.
+	DryRun bool
+	// AddedBy is recorded on repos added for orphans; default "reconcile".
+	AddedBy string
+}
+
+// ReconcileOptionsFromConfig returns ReconcileOptions searching
+// cfg.ExternalRoot.
+func ReconcileOptionsFromConfig(cfg *config.Config) ReconcileOptions {
+	return ReconcileOptions{Root: config.ExpandPath(cfg.ExternalRoot)}
+}
+
+// Report is the outcome of a Reconcile.
+type Report struct {
+	// Clones is the number of git repositories found under Root.
+	Clones int
+	// Issues are ordered by repo name, then orphans by path.
+	Issues []Issue
+	// Fixed and Failed count applied fixes; both are 0 for a dry run.
+	Fixed  int
+	Failed int
+}
+
+// Reconcile compares the repos in the store with the git repositories under
+// opts.Root, matching clones to repos by recorded path or origin remote
+// (see store.ReposStore.GetByURL), and fixes the differences unless
+// opts.DryRun is set. Repos whose recorded clone lies outside Root are
+// checked in place. Per-issue failures are reported in the issues; the
+// error is for failures to read the store or walk Root.
+func Reconcile(ctx context.Context, repos *store.ReposStore, opts ReconcileOptions) (*Report, error) {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 3
+	}
+	if opts.AddedBy == "" {
+		opts.AddedBy = "reconcile"
+	}
+	clones, err := findClones(opts.Root, opts.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := repos.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].Name < stored[j].Name })
+
+	byPath := make(map[string]string, len(stored))
+	for _, r := range stored {
+		if r.ClonePath != "" {
+			byPath[filepath.Clean(r.ClonePath)] = r.Name
+		}
+	}
+	found := make(map[string][]string)
+	var orphans []Issue
+	// First orphan clone of each origin, by canonical URL; only it is adopted.
+	orphanOf := make(map[string]string)
+	for _, path := range clones {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if name, ok := byPath[path]; ok {
+			found[name] = append(found[name], path)
+			continue
+		}
+		remote, _ := git.GetRemoteURL(ctx, path)
+		if remote != "" {
+			r, err := repos.GetByURL(ctx, remote)
+			if err == nil {
+				found[r.Name] = append(found[r.Name], path)
+				continue
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return nil, err
+			}
+			canonical := git.CanonicalURL(remote)
+			if first, ok := orphanOf[canonical]; ok {
+				orphans = append(orphans, Issue{Kind: IssueDuplicateClone, Path: path, URL: remote, Detail: "another clone of " + first})
+				continue
+			}
+			orphanOf[canonical] = path
+		}
+		orphans = append(orphans, Issue{Kind: IssueOrphan, Path: path, URL: remote, Detail: "clone is not in the store"})
+	}
+
+	rep := &Report{Clones: len(clones)}
+	for _, r := range stored {
+		rep.Issues = append(rep.Issues, checkRepo(ctx, r, found[r.Name], opts.Root)...)
+	}
+	rep.Issues = append(rep.Issues, orphans...)
+
+	if opts.DryRun {
+		return rep, nil
+	}
+	for i := range rep.Issues {
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		is := &rep.Issues[i]
+		if is.Kind == IssueDuplicateClone {
+			continue
+		}
+		is.Err = fix(ctx, repos, is, opts.AddedBy)
+		if is.Err != nil {
+			rep.Failed++
+		} else {
+			is.Fixed = true
+			rep.Fixed++
+		}
+	}
+	return rep, nil
+}
+
+// findClones returns the git repositories under root, not descending into
+// them, hidden directories or below maxDepth. A missing root has none.
+func findClones(root string, maxDepth int) ([]string, error) {
+	if root == "" {
+		return nil, errors.New("reconcile: no root directory")
+	}
+	root = filepath.Clean(root)
+	var clones []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return fs.SkipDir
+		}
+		if git.IsGitRepo(path) {
+			clones = append(clones, path)
+			return fs.SkipDir
+		}
+		rel, _ := filepath.Rel(root, path)
+		if rel != "." && strings.Count(rel, string(filepath.Separator))+1 >= maxDepth {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk %s: %w", root, err)
+	}
+	return clones, nil
+}
+
+// checkRepo compares a stored repo with the clones of it found under root.
+func checkRepo(ctx context.Context, r store.Repo, found []string, root string) []Issue {
+	recorded := ""
+	if r.ClonePath != "" {
+		recorded = filepath.Clean(r.ClonePath)
+	}
+	// A recorded clone outside root was not walked, so look at it directly.
+	if recorded != "" && !contains(found, recorded) && git.IsGitRepo(recorded) {
+		found = append([]string{recorded}, found...)
+	}
+
+	if r.Cloned && contains(found, recorded) {
+		issues := checkShallow(ctx, r, recorded)
+		for _, path := range found {
+			if path != recorded {
+				issues = append(issues, Issue{Kind: IssueDuplicateClone, Repo: r.Name, Path: path,
+					Detail: "another clone of " + recorded})
+			}
+		}
+		return issues
+	}
+
+	if len(found) == 0 {
+		if !r.Cloned {
+			return nil
+		}
+		return []Issue{{Kind: IssueMissing, Repo: r.Name, Path: r.ClonePath, Detail: "recorded clone is gone"}}
+	}
+
+	// Prefer the clone where Sync would have put it.
+	path := found[0]
+	if want := filepath.Join(root, r.Name); contains(found, want) {
+		path = want
+	}
+	detail := "clone found but repo not marked cloned"
+	if r.Cloned {
+		detail = fmt.Sprintf("clone moved from %s", r.ClonePath)
+	}
+	issues := []Issue{{Kind: IssuePathMismatch, Repo: r.Name, Path: path, Detail: detail}}
+	for _, p := range found {
+		if p != path {
+			issues = append(issues, Issue{Kind: IssueDuplicateClone, Repo: r.Name, Path: p, Detail: "another clone of " + path})
+		}
+	}
+	return issues
+}
+
+// checkShallow reports a clone whose shallowness differs from the store's.
+// A clone git cannot read is left alone, like one without a remote.
+func checkShallow(ctx context.Context, r store.Repo, path string) []Issue {
+	shallow, err := git.IsShallow(ctx, path)
+	if err != nil || shallow == r.Shallow {
+		return nil
+	}
+	detail := "clone is full but recorded as shallow"
+	if shallow {
+		detail = "clone is shallow but recorded as full"
+	}
+	return []Issue{{Kind: IssueShallow, Repo: r.Name, Path: path, Detail: detail}}
+}
+
+func contains(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// fix applies the store change that resolves is.
+func fix(ctx context.Context, repos *store.ReposStore, is *Issue, addedBy string) error {
+	switch is.Kind {
+	case IssueMissing:
+		return repos.MarkNotCloned(ctx, is.Repo)
+	case IssuePathMismatch:
+		if err := repos.SetClonePath(ctx, is.Repo, is.Path); err != nil {
+			return err
+		}
+		_, err := repos.UpdateFromClone(ctx, is.Repo, is.Path)
+		return err
+	case IssueShallow:
+		_, err := repos.UpdateFromClone(ctx, is.Repo, is.Path)
+		return err
+	case IssueOrphan:
+		name, err := adopt(ctx, repos, is.Path, is.URL, addedBy)
+		if err != nil {
+			return err
+		}
+		is.Repo = name
+		return nil
+	}
+	return fmt.Errorf("no fix for %s", is.Kind)
+}
+
+// adopt adds a repo for the orphaned clone at path and returns its name. The
+// repo is added whole or not at all.
+func adopt(ctx context.Context, repos *store.ReposStore, path, url, addedBy string) (string, error) {
+	if url == "" {
+		return "", errors.New("clone has no origin remote")
+	}
+	repo := store.Repo{URL: url, AddedAt: time.Now().Unix(), AddedBy: addedBy}
+	candidates := []string{filepath.Base(path)}
+	if parsed, err := git.ParseGitURL(url); err == nil {
+		repo.Platform, repo.Owner, repo.Repo = parsed.Platform, parsed.Owner, parsed.Name
+		candidates = append(candidates, parsed.Name, parsed.Owner+"-"+parsed.Name)
+	}
+	err := db.InTx(ctx, repos.DB, func(tx *sql.Tx) error {
+		repos := store.NewReposStore(tx)
+		for _, name := range candidates {
+			_, err := repos.Get(ctx, name)
+			if errors.Is(err, sql.ErrNoRows) {
+				repo.Name = name
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if repo.Name == "" {
+			return fmt.Errorf("name %q is taken", filepath.Base(path))
+		}
+
+		if err := repos.Upsert(ctx, repo); err != nil {
+			return err
+		}
+		if err := repos.SetClonePath(ctx, repo.Name, path); err != nil {
+			return err
+		}
+		_, err := repos.UpdateFromClone(ctx, repo.Name, path)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return repo.Name, nil
+}
@@ -0,0 +1,364 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+// Package reposync clones and updates many repositories at once with a
+// bounded pool of git processes, recording the results in store.ReposStore,
+// and reconciles the store with the clones found on disk.
+package reposync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/arc-sdk/config"
+	"github.com/yourorg/arc-sdk/git"
+	"github.com/yourorg/arc-sdk/store"
+)
+
+// Action is what Sync does to a repo.
+type Action string
+
+const (
+	ActionClone Action = "clone"
+	ActionPull  Action = "pull"
+	ActionFetch Action = "fetch"
+)
+
+// EventKind tells progress events apart.
+type EventKind string
+
+const (
+	EventStarted  EventKind = "started"
+	EventRetrying EventKind = "retrying"
+	EventFinished EventKind = "finished"
+)
+
+// Event reports progress on one repo.
+type Event struct {
+	Kind    EventKind
+	Repo    string
+	Action  Action
+	Attempt int
+	// Err is the failed attempt's error for EventRetrying and the final
+	// error, if any, for EventFinished.
+	Err error
+	// Done counts finished repos out of Total, including this one.
+	Done  int
+	Total int
+}
+
+// Options configures a Syncer.
+type Options struct {
+	// Root is where repos without a ClonePath are cloned, as Root/<name>.
+	Root string
+	// Concurrency bounds how many git commands run at once; default 4.
+	Concurrency int
+	// FetchOnly fetches cloned repos instead of pulling them.
+	FetchOnly bool
+	// Shallow makes new clones with --depth 1.
+	Shallow bool
+	// Timeout bounds each attempt; 0 means no limit.
+	Timeout time.Duration
+	// Retries is how many more times a transient failure is attempted.
+	Retries int
+	// RetryDelay is the wait before the first retry, doubling for each
+	// later one; default 2s.
+	RetryDelay time.Duration
+	// Progress, if set, receives events. Calls are serialized.
+	Progress func(Event)
+}
+
+// OptionsFromConfig returns Options cloning under cfg.ExternalRoot with
+// cfg.Concurrency.Fetch workers.
+func OptionsFromConfig(cfg *config.Config) Options {
+	return Options{
+		Root:        config.ExpandPath(cfg.ExternalRoot),
+		Concurrency: cfg.Concurrency.Fetch,
+	}
+}
+
+// Result is the outcome of syncing one repo.
+type Result struct {
+	Name     string
+	Action   Action
+	Path     string
+	Attempts int
+	Duration time.Duration
+	// Log holds git's combined output from every attempt.
+	Log string
+	Err error
+	// CommitCount and LastCommitAt are read from the clone after success.
+	CommitCount  int
+	LastCommitAt int64
+}
+
+// Summary is the outcome of a Sync.
+type Summary struct {
+	// Results are in the order the repos were given.
+	Results []Result
+	// Cloned, Updated and Failed count results by outcome; fetches count
+	// as updates.
+	Cloned  int
+	Updated int
+	Failed  int
+}
+
+// Syncer clones missing repos and pulls or fetches cloned ones.
+type Syncer struct {
+	Repos *store.ReposStore
+	Opts  Options
+
+	mu sync.Mutex
+}
+
+// NewSyncer creates a Syncer recording results in repos.
+func NewSyncer(repos *store.ReposStore, opts Options) *Syncer {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = 2 * time.Second
+	}
+	return &Syncer{Repos: repos, Opts: opts}
+}
+
+// Sync brings every repo up to date. Repos whose clone directory holds no
+// git repository are cloned; the rest are pulled (or fetched). On success
+// the repo is marked cloned or updated and its git facts are refreshed with
+// ReposStore.UpdateFromClone. Failures are reported per repo in the summary;
+// the error is only for cancellation.
+func (s *Syncer) Sync(ctx context.Context, repos []store.Repo) (*Summary, error) {
+	sum := &Summary{Results: make([]Result, len(repos))}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	done := 0
+
+	workers := min(s.Opts.Concurrency, len(repos))
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res := s.syncOne(ctx, repos[i])
+				s.mu.Lock()
+				sum.Results[i] = res
+				done++
+				s.emitLocked(Event{
+					Kind: EventFinished, Repo: res.Name, Action: res.Action, Attempt: res.Attempts,
+					Err: res.Err, Done: done, Total: len(repos),
+				})
+				s.mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := range repos {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, res := range sum.Results {
+		switch {
+		case res.Name == "":
+			// Never started because ctx was cancelled.
+			sum.Results[i] = Result{Name: repos[i].Name, Err: ctx.Err()}
+			sum.Failed++
+		case res.Err != nil:
+			sum.Failed++
+		case res.Action == ActionClone:
+			sum.Cloned++
+		default:
+			sum.Updated++
+		}
+	}
+	return sum, ctx.Err()
+}
+
+func (s *Syncer) emitLocked(e Event) {
+	if s.Opts.Progress != nil {
+		s.Opts.Progress(e)
+	}
+}
+
+func (s *Syncer) emit(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.emitLocked(e)
+}
+
+func (s *Syncer) syncOne(ctx context.Context, repo store.Repo) Result {
+	start := time.Now()
+	res := Result{Name: repo.Name, Path: repo.ClonePath}
+	if res.Path == "" {
+		res.Path = filepath.Join(s.Opts.Root, repo.Name)
+	}
+	// An existing clone is updated even if the store has not caught up.
+	res.Action = ActionClone
+	if git.IsGitRepo(res.Path) {
+		res.Action = ActionPull
+		if s.Opts.FetchOnly {
+			res.Action = ActionFetch
+		}
+	}
+
+	var log bytes.Buffer
+	res.Err = s.run(ctx, repo, &res, &log)
+	res.Log = log.String()
+	if res.Err == nil {
+		res.Err = s.record(ctx, repo, &res)
+	}
+	res.Duration = time.Since(start)
+	return res
+}
+
+// run performs res.Action with retries, writing git's output to log.
+func (s *Syncer) run(ctx context.Context, repo store.Repo, res *Result, log *bytes.Buffer) error {
+	if res.Action == ActionClone {
+		if err := checkCloneDest(res.Path); err != nil {
+			return err
+		}
+	}
+	opts := git.RunOptions{
+		Stdout: log,
+		Stderr: log,
+		// Never block a worker on a credential prompt.
+		Env: []string{"GIT_TERMINAL_PROMPT=0"},
+	}
+
+	s.emit(Event{Kind: EventStarted, Repo: repo.Name, Action: res.Action, Attempt: 1})
+	delay := s.Opts.RetryDelay
+	for attempt := 1; ; attempt++ {
+		res.Attempts = attempt
+		fmt.Fprintf(log, "--- %s %s (attempt %d)\n", res.Action, repo.Name, attempt)
+		logStart := log.Len()
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if s.Opts.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, s.Opts.Timeout)
+		}
+		var err error
+		switch res.Action {
+		case ActionClone:
+			depth := 0
+			if s.Opts.Shallow {
+				depth = 1
+			}
+			err = git.CloneWithOptions(attemptCtx, repo.URL, res.Path, depth, opts)
+			if err != nil {
+				// Let the next attempt start from an empty destination.
+				os.RemoveAll(res.Path)
+			}
+		case ActionPull:
+			err = git.PullWithOptions(attemptCtx, res.Path, opts)
+		case ActionFetch:
+			err = git.FetchWithOptions(attemptCtx, res.Path, opts)
+		}
+		timedOut := attemptCtx.Err() != nil && ctx.Err() == nil
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if timedOut {
+			err = fmt.Errorf("%w after %s", context.DeadlineExceeded, s.Opts.Timeout)
+		}
+		if attempt > s.Opts.Retries || !timedOut && !transient(log.String()[logStart:]) {
+			return err
+		}
+
+		s.emit(Event{Kind: EventRetrying, Repo: repo.Name, Action: res.Action, Attempt: attempt, Err: err})
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// checkCloneDest refuses to clone into a directory that already has content,
+// since a failed attempt removes the destination.
+func checkCloneDest(path string) error {
+	entries, err := os.ReadDir(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("clone destination %s exists and is not empty", path)
+	}
+	return nil
+}
+
+// record stores a successful sync and reads back the clone's git facts.
+// A fetch leaves the working tree, and so updated_at, alone.
+func (s *Syncer) record(ctx context.Context, repo store.Repo, res *Result) error {
+	var err error
+	switch {
+	case res.Action == ActionClone || !repo.Cloned || repo.ClonePath != res.Path:
+		err = s.Repos.MarkCloned(ctx, res.Name, res.Path)
+	case res.Action == ActionPull:
+		err = s.Repos.MarkUpdated(ctx, res.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("record %s: %w", res.Name, err)
+	}
+	in, err := s.Repos.UpdateFromClone(ctx, res.Name, res.Path)
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", res.Name, err)
+	}
+	res.CommitCount = in.CommitCount
+	res.LastCommitAt = in.LastCommitAt
+	return nil
+}
+
+// transientMarkers are git and curl messages for failures worth retrying.
+var transientMarkers = []string{
+	"could not resolve host",
+	"connection timed out",
+	"connection reset",
+	"connection refused",
+	"operation timed out",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"rpc failed",
+	"temporary failure",
+	"gnutls",
+	"ssl_read",
+	"http 429",
+	"error: 500",
+	"error: 502",
+	"error: 503",
+	"error: 504",
+}
+
+// transient reports whether git's output shows a network or server failure
+// that may succeed when retried.
+func transient(output string) bool {
+	output = strings.ToLower(output)
+	for _, m := range transientMarkers {
+		if strings.Contains(output, m) {
+			return true
+		}
+	}
+	return false
+}
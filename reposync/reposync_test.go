@@ -0,0 +1,255 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package reposync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourorg/arc-sdk/db"
+	"github.com/yourorg/arc-sdk/store"
+)
+
+func runGit(t *testing.T, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// newRemote creates a bare repo with one commit and returns its path and a
+// work tree that pushes to it.
+func newRemote(t *testing.T, name string) (bare, work string) {
+	t.Helper()
+	dir := t.TempDir()
+	bare = filepath.Join(dir, name+".git")
+	work = filepath.Join(dir, name)
+	runGit(t, "init", "-q", "--bare", "-b", "main", bare)
+	runGit(t, "clone", "-q", bare, work)
+	if err := os.WriteFile(filepath.Join(work, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "-C", work, "add", ".")
+	runGit(t, "-C", work, "commit", "-q", "-m", "init")
+	runGit(t, "-C", work, "push", "-q", "origin", "main")
+	return bare, work
+}
+
+func TestSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	d, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d.Close()
+	repos := store.NewReposStore(d)
+	root := t.TempDir()
+
+	alpha, _ := newRemote(t, "alpha")
+	beta, betaWork := newRemote(t, "beta")
+	for name, url := range map[string]string{
+		"alpha": alpha,
+		"beta":  beta,
+		"gone":  filepath.Join(t.TempDir(), "missing.git"),
+	} {
+		if err := repos.Upsert(ctx, store.Repo{Name: name, URL: url, AddedAt: 1}); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+	list := func() []store.Repo {
+		all, err := repos.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		return all
+	}
+
+	var mu sync.Mutex
+	var events []Event
+	syncer := NewSyncer(repos, Options{
+		Root:        root,
+		Concurrency: 2,
+		Timeout:     time.Minute,
+		Retries:     2,
+		RetryDelay:  time.Millisecond,
+		Progress: func(e Event) {
+			mu.Lock()
+			events = append(events, e)
+			mu.Unlock()
+		},
+	})
+	sum, err := syncer.Sync(ctx, list())
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if sum.Cloned != 2 || sum.Failed != 1 {
+		t.Fatalf("summary = %+v", sum)
+	}
+	for _, res := range sum.Results {
+		if res.Name == "gone" {
+			// Not a transient failure, so it is not retried.
+			if res.Err == nil || res.Attempts != 1 || !strings.Contains(res.Log, "missing.git") {
+				t.Fatalf("gone result = %+v", res)
+			}
+		} else if res.Err != nil || res.Action != ActionClone || res.CommitCount != 1 {
+			t.Fatalf("%s result = %+v", res.Name, res)
+		}
+	}
+	if len(events) != 6 || events[len(events)-1].Done != 3 || events[len(events)-1].Total != 3 {
+		t.Fatalf("events = %+v", events)
+	}
+
+	got, _ := repos.Get(ctx, "beta")
+	if !got.Cloned || got.ClonePath != filepath.Join(root, "beta") || got.CommitCount != 1 || got.DefaultBranch != "main" {
+		t.Fatalf("beta after clone = %+v", got)
+	}
+
+	// A second sync pulls new commits into existing clones.
+	runGit(t, "-C", betaWork, "commit", "-q", "--allow-empty", "-m", "second")
+	runGit(t, "-C", betaWork, "push", "-q", "origin", "main")
+	sum, err = syncer.Sync(ctx, []store.Repo{*got})
+	if err != nil || sum.Updated != 1 || sum.Results[0].Action != ActionPull || sum.Results[0].CommitCount != 2 {
+		t.Fatalf("pull summary = %+v, %v", sum, err)
+	}
+	if got, _ = repos.Get(ctx, "beta"); got.CommitCount != 2 || got.UpdatedAt == 0 {
+		t.Fatalf("beta after pull = %+v", got)
+	}
+
+	// A clone is never attempted over unrelated files.
+	gone, _ := repos.Get(ctx, "gone")
+	if err := os.WriteFile(filepath.Join(root, "gone"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if sum, _ := syncer.Sync(ctx, []store.Repo{*gone}); sum.Failed != 1 || sum.Results[0].Attempts != 0 {
+		t.Fatalf("sync over file = %+v", sum)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if sum, err := syncer.Sync(cancelled, list()); err == nil || sum.Failed != 3 {
+		t.Fatalf("cancelled sync = %+v, %v", sum, err)
+	}
+}
+
+func TestTransient(t *testing.T) {
+	for out, want := range map[string]bool{
+		"fatal: unable to access 'https://x/': Could not resolve host: x": true,
+		"error: RPC failed; curl 56 GnuTLS recv error (-9)":               true,
+		"fatal: the remote end hung up unexpectedly":                      true,
+		"remote: Repository not found.\nfatal: repository 'x' not found":  false,
+		"fatal: Authentication failed for 'https://x/'":                   false,
+	} {
+		if got := transient(out); got != want {
+			t.Errorf("transient(%q) = %v, want %v", out, got, want)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	d, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d.Close()
+	repos := store.NewReposStore(d)
+	root := t.TempDir()
+
+	alpha, _ := newRemote(t, "alpha")
+	beta, _ := newRemote(t, "beta")
+	orphan, _ := newRemote(t, "orphan")
+	runGit(t, "clone", "-q", alpha, filepath.Join(root, "alpha"))
+	runGit(t, "clone", "-q", beta, filepath.Join(root, "moved", "beta"))
+	runGit(t, "clone", "-q", orphan, filepath.Join(root, "example.com", "acme", "orphan"))
+	runGit(t, "clone", "-q", orphan, filepath.Join(root, ".cache", "orphan"))
+	runGit(t, "clone", "-q", orphan, filepath.Join(root, "orphan-copy"))
+	for _, r := range []store.Repo{
+		{Name: "alpha", URL: alpha, Cloned: true, ClonePath: filepath.Join(root, "alpha"), Shallow: true},
+		{Name: "beta", URL: beta, Cloned: true, ClonePath: filepath.Join(root, "beta")},
+		{Name: "gone", URL: "https://github.com/x/gone", Cloned: true, ClonePath: filepath.Join(root, "gone")},
+		{Name: "remote-only", URL: "https://github.com/x/remote-only"},
+	} {
+		r.AddedAt = 1
+		if err := repos.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert %s: %v", r.Name, err)
+		}
+	}
+
+	kinds := func(rep *Report) string {
+		var out []string
+		for _, is := range rep.Issues {
+			out = append(out, string(is.Kind)+":"+is.Repo)
+		}
+		return strings.Join(out, " ")
+	}
+	want := "shallow:alpha path-mismatch:beta missing:gone orphan: duplicate-clone:"
+	rep, err := Reconcile(ctx, repos, ReconcileOptions{Root: root, DryRun: true})
+	if err != nil {
+		t.Fatalf("Reconcile dry run: %v", err)
+	}
+	if rep.Clones != 4 || kinds(rep) != want || rep.Fixed != 0 {
+		t.Fatalf("dry run = %d clones, %s", rep.Clones, kinds(rep))
+	}
+	if n, _ := repos.Count(ctx); n != 4 {
+		t.Fatalf("dry run changed the store: %d repos", n)
+	}
+
+	rep, err = Reconcile(ctx, repos, ReconcileOptions{Root: root})
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if rep.Fixed != 4 || rep.Failed != 0 || rep.Issues[3].Repo != "orphan" {
+		t.Fatalf("apply = %+v", rep)
+	}
+	if b, _ := repos.Get(ctx, "beta"); b.ClonePath != filepath.Join(root, "moved", "beta") || b.CommitCount != 1 {
+		t.Fatalf("beta = %+v", b)
+	}
+	if g, _ := repos.Get(ctx, "gone"); g.Cloned || g.ClonePath != "" {
+		t.Fatalf("gone = %+v", g)
+	}
+	if o, err := repos.Get(ctx, "orphan"); err != nil || !o.Cloned || o.URL != orphan || o.AddedBy != "reconcile" {
+		t.Fatalf("orphan = %+v, %v", o, err)
+	}
+
+	if n, _ := repos.Count(ctx); n != 5 {
+		t.Fatalf("apply adopted the orphan's duplicate: %d repos", n)
+	}
+
+	// Only the duplicate clone, left for the user to delete, remains.
+	rep, err = Reconcile(ctx, repos, ReconcileOptions{Root: root, DryRun: true})
+	if err != nil || kinds(rep) != "duplicate-clone:orphan" {
+		t.Fatalf("after apply = %s, %v", kinds(rep), err)
+	}
+
+	// An orphan that cannot be inspected, here for a detached HEAD without
+	// origin/HEAD, is not adopted at all.
+	root2 := t.TempDir()
+	broken := filepath.Join(root2, "broken")
+	runGit(t, "init", "-q", broken)
+	runGit(t, "-C", broken, "commit", "-q", "--allow-empty", "-m", "init")
+	runGit(t, "-C", broken, "checkout", "-q", "--detach")
+	runGit(t, "-C", broken, "remote", "add", "origin", "https://github.com/x/broken")
+	rep, err = Reconcile(ctx, repos, ReconcileOptions{Root: root2})
+	if err != nil || rep.Failed != 1 || rep.Issues[len(rep.Issues)-1].Kind != IssueOrphan {
+		t.Fatalf("broken orphan = %+v, %v", rep, err)
+	}
+	if _, err := repos.Get(ctx, "broken"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("half-adopted broken repo: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package secrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yourorg/arc-sdk/store"
+)
+
+const (
+	// KeyEnv holds a base64-encoded key that takes precedence over the key file.
+	KeyEnv = "ARC_SECRET_KEY"
+	// OldKeysEnv holds comma-separated base64 keys still accepted for decryption.
+	OldKeysEnv = "ARC_SECRET_KEY_OLD"
+)
+
+// oldKeySuffix names the file holding previous keys while a rotation is in
+// progress.
+const oldKeySuffix = ".old"
+
+// DefaultKeyPath returns $XDG_CONFIG_HOME/arc/secret.key, falling back to
+// ~/.config/arc/secret.key.
+func DefaultKeyPath() string {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, _ := os.UserHomeDir()
+		base = filepath.Join(home, ".config")
+	}
+	return filepath.Join(base, "arc", "secret.key")
+}
+
+// GenerateKey returns a new random encryption key.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, store.EncryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Keys is the key material a Store encrypts with.
+type Keys struct {
+	Current []byte
+	// Old keys decrypt values not yet re-encrypted with Current.
+	Old [][]byte
+	// Path is the key file Current was read from; empty when it came from
+	// the environment.
+	Path string
+}
+
+// LoadKeys reads the key from $ARC_SECRET_KEY, or else from the key file at
+// path (DefaultKeyPath if empty), creating the file with a fresh key and mode
+// 0600 if it does not exist.
+func LoadKeys(path string) (*Keys, error) {
+	if env := os.Getenv(KeyEnv); env != "" {
+		current, err := decodeKey(env)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", KeyEnv, err)
+		}
+		keys := &Keys{Current: current}
+		for _, s := range strings.Split(os.Getenv(OldKeysEnv), ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			old, err := decodeKey(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", OldKeysEnv, err)
+			}
+			keys.Old = append(keys.Old, old)
+		}
+		return keys, nil
+	}
+
+	if path == "" {
+		path = DefaultKeyPath()
+	}
+	current, err := readKeyFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if current, err = GenerateKey(); err != nil {
+			return nil, err
+		}
+		if err := writeKeyFile(path, current); err != nil {
+			return nil, err
+		}
+		return &Keys{Current: current, Path: path}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := &Keys{Current: current, Path: path}
+	if keys.Old, err = readKeyLines(path + oldKeySuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func decodeKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("decode key: %w", err)
+	}
+	if len(key) != store.EncryptionKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", store.EncryptionKeySize, len(key))
+	}
+	return key, nil
+}
+
+// readKeyFile reads a key file holding exactly one key.
+func readKeyFile(path string) ([]byte, error) {
+	keys, err := readKeyLines(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) != 1 {
+		return nil, fmt.Errorf("%s: expected one key, found %d", path, len(keys))
+	}
+	return keys[0], nil
+}
+
+// readKeyLines reads one base64 key per line.
+func readKeyLines(path string) ([][]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys [][]byte
+	for _, line := range bytes.Split(raw, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		key, err := decodeKey(string(line))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// writeKeyFile atomically replaces path with keys, one per line, mode 0600.
+func writeKeyFile(path string, keys ...[]byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, k := range keys {
+		buf.WriteString(base64.StdEncoding.EncodeToString(k))
+		buf.WriteByte('\n')
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
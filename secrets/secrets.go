@@ -0,0 +1,131 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+// Package secrets keeps named credentials (API keys, bot tokens) encrypted in
+// a KVStore instead of in plaintext configuration. Config files refer to them
+// as secret://<name>; see config.Config.ResolveSecrets.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yourorg/arc-sdk/store"
+)
+
+// namespace is the KV namespace secrets are stored under.
+const namespace = "secret"
+
+// ErrNotFound is returned by Get for unknown names.
+var ErrNotFound = errors.New("secret not found")
+
+// Store reads and writes secrets.
+type Store struct {
+	enc  *store.EncryptedStore
+	keys *Keys
+}
+
+// New returns a Store that keeps secrets in kv, encrypted with keys. Closing
+// kv is left to the caller.
+func New(kv store.KVStore, keys *Keys) (*Store, error) {
+	enc, err := store.NewEncryptedStore(store.Namespaced(kv, namespace), keys.Current, keys.Old...)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{enc: enc, keys: keys}, nil
+}
+
+// Open is New with keys from LoadKeys(keyPath).
+func Open(kv store.KVStore, keyPath string) (*Store, error) {
+	keys, err := LoadKeys(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	return New(kv, keys)
+}
+
+func validName(name string) error {
+	if name == "" || strings.TrimSpace(name) != name {
+		return fmt.Errorf("invalid secret name %q", name)
+	}
+	return nil
+}
+
+// Set stores value under name, replacing any previous value.
+func (s *Store) Set(ctx context.Context, name, value string) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	return s.enc.Set(ctx, name, []byte(value))
+}
+
+// Get returns the value stored under name.
+func (s *Store) Get(ctx context.Context, name string) (string, error) {
+	value, err := s.enc.Get(ctx, name)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// Delete removes name; deleting an unknown name is not an error.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	return s.enc.Delete(ctx, name)
+}
+
+// List returns the names of all stored secrets, sorted. Values are not
+// decrypted.
+func (s *Store) List(ctx context.Context) ([]string, error) {
+	return s.enc.List(ctx, "")
+}
+
+// Rotate generates a new key, re-encrypts every secret with it and returns
+// how many were rewritten.
+//
+// For a key file, the previous keys are kept in "<path>.old" until every
+// value has been re-encrypted, so an interrupted rotation can be resumed by
+// running it again. Keys from the environment cannot be rotated here; use
+// RotateTo and update $ARC_SECRET_KEY.
+func (s *Store) Rotate(ctx context.Context) (int, error) {
+	if s.keys.Path == "" {
+		return 0, fmt.Errorf("rotate: key comes from %s, use RotateTo", KeyEnv)
+	}
+	newKey, err := GenerateKey()
+	if err != nil {
+		return 0, err
+	}
+	old := append([][]byte{s.keys.Current}, s.keys.Old...)
+	if err := writeKeyFile(s.keys.Path+oldKeySuffix, old...); err != nil {
+		return 0, fmt.Errorf("save previous keys: %w", err)
+	}
+	if err := writeKeyFile(s.keys.Path, newKey); err != nil {
+		return 0, fmt.Errorf("save new key: %w", err)
+	}
+	n, err := s.RotateTo(ctx, newKey)
+	if err != nil {
+		return n, err
+	}
+	if err := os.Remove(s.keys.Path + oldKeySuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return n, err
+	}
+	s.keys.Old = nil
+	return n, nil
+}
+
+// RotateTo re-encrypts every secret with newKey, which the caller is
+// responsible for persisting.
+func (s *Store) RotateTo(ctx context.Context, newKey []byte) (int, error) {
+	n, err := s.enc.Rotate(ctx, newKey)
+	if err != nil {
+		return n, fmt.Errorf("rotate: %w", err)
+	}
+	s.keys.Old = append([][]byte{s.keys.Current}, s.keys.Old...)
+	s.keys.Current = newKey
+	return n, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourorg/arc-sdk/config"
+	"github.com/yourorg/arc-sdk/store"
+)
+
+func TestSecretsRoundTripAndRotate(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(KeyEnv, "")
+	keyPath := filepath.Join(t.TempDir(), "arc", "secret.key")
+	kv := store.NewMemoryStore()
+	defer kv.Close()
+
+	s, err := Open(kv, keyPath)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if info, err := os.Stat(keyPath); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("key file not created 0600: %v, %v", info, err)
+	}
+	kv.Set(ctx, "unrelated", []byte("plain"))
+	if err := s.Set(ctx, "discord.bot_token", "bot-123"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := s.Set(ctx, "ai.api_key", "sk-abc"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if names, _ := s.List(ctx); strings.Join(names, ",") != "ai.api_key,discord.bot_token" {
+		t.Fatalf("List = %v", names)
+	}
+	if _, err := s.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if n, err := s.Rotate(ctx); err != nil || n != 2 {
+		t.Fatalf("Rotate = %d, %v", n, err)
+	}
+	if _, err := os.Stat(keyPath + oldKeySuffix); !os.IsNotExist(err) {
+		t.Fatalf("old key file left behind: %v", err)
+	}
+	// A fresh handle with only the rotated key file still reads everything.
+	reopened, err := Open(kv, keyPath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+
+	cfg := &config.Config{
+		AI: config.AIConfig{APIKey: "secret://ai.api_key"},
+		Discord: config.DiscordConfig{
+			BotToken: "secret://discord.bot_token",
+			Webhooks: map[string]string{"ops": "https://example.invalid/hook"},
+		},
+	}
+	if err := cfg.ResolveSecrets(ctx, reopened); err != nil {
+		t.Fatalf("ResolveSecrets: %v", err)
+	}
+	if cfg.AI.APIKey != "sk-abc" || cfg.Discord.BotToken != "bot-123" || cfg.Discord.Webhooks["ops"] != "https://example.invalid/hook" {
+		t.Fatalf("resolved config = %+v", cfg)
+	}
+	cfg.Discord.BotToken = "secret://nope"
+	if err := cfg.ResolveSecrets(ctx, reopened); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for missing reference, got %v", err)
+	}
+}
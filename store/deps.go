@@ -8,6 +8,8 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/yourorg/arc-sdk/db"
 )
 
 // Dependency represents a repository dependency.
@@ -32,57 +34,48 @@ type RepoDependency struct {
 
 // DepsStore manages repository dependencies in the database.
 type DepsStore struct {
-	DB *sql.DB
+	DB db.Querier
 }
 
 // NewDepsStore creates a new DepsStore.
-func NewDepsStore(db *sql.DB) *DepsStore {
-	return &DepsStore{DB: db}
+func NewDepsStore(q db.Querier) *DepsStore {
+	return &DepsStore{DB: q}
 }
 
 // UpsertDependencies replaces all dependencies for a repo with the new set.
 func (s *DepsStore) UpsertDependencies(ctx context.Context, repoName string, dependencies []Dependency) error {
-	tx, err := s.DB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	// Delete existing dependencies for this repo
-	_, err = tx.ExecContext(ctx, `DELETE FROM repo_dependencies WHERE repo_name = ?`, repoName)
-	if err != nil {
-		return fmt.Errorf("delete existing dependencies: %w", err)
-	}
-
-	// Insert new dependencies
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO repo_dependencies(repo_name, dependency_name, dependency_version, ecosystem, dependency_type, detected_at)
-		VALUES(?, ?, ?, ?, ?, ?)
-	`)
-	if err != nil {
-		return fmt.Errorf("prepare insert: %w", err)
-	}
-	defer stmt.Close()
-
-	for _, dep := range dependencies {
-		_, err := stmt.ExecContext(ctx,
-			repoName,
-			dep.Name,
-			dep.Version,
-			dep.Ecosystem,
-			dep.Type,
-			dep.DetectedAt.Unix(),
-		)
+	return db.InTx(ctx, s.DB, func(tx *sql.Tx) error {
+		// Delete existing dependencies for this repo
+		_, err := tx.ExecContext(ctx, `DELETE FROM repo_dependencies WHERE repo_name = ?`, repoName)
 		if err != nil {
-			return fmt.Errorf("insert dependency %s: %w", dep.Name, err)
+			return fmt.Errorf("delete existing dependencies: %w", err)
 		}
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
-	}
 
-	return nil
+		// Insert new dependencies
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO repo_dependencies(repo_name, dependency_name, dependency_version, ecosystem, dependency_type, detected_at)
+			VALUES(?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("prepare insert: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, dep := range dependencies {
+			_, err := stmt.ExecContext(ctx,
+				repoName,
+				dep.Name,
+				dep.Version,
+				dep.Ecosystem,
+				dep.Type,
+				dep.DetectedAt.Unix(),
+			)
+			if err != nil {
+				return fmt.Errorf("insert dependency %s: %w", dep.Name, err)
+			}
+		}
+		return nil
+	})
 }
 
 // GetDependencies returns all dependencies for a repo.
@@ -5,7 +5,8 @@ package store
 
 import (
 	"context"
-	"database/sql"
+
+	"github.com/yourorg/arc-sdk/db"
 )
 
 // EnvBackup represents an environment file backup record.
@@ -19,12 +20,12 @@ type EnvBackup struct {
 
 // EnvStore manages environment backup records in the database.
 type EnvStore struct {
-	DB *sql.DB
+	DB db.Querier
 }
 
 // NewEnvStore creates a new EnvStore.
-func NewEnvStore(db *sql.DB) *EnvStore {
-	return &EnvStore{DB: db}
+func NewEnvStore(q db.Querier) *EnvStore {
+	return &EnvStore{DB: q}
 }
 
 // UpsertBackup inserts or updates an environment backup record.
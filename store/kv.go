@@ -4,18 +4,26 @@
 package store
 
 import (
+	"container/list"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/yourorg/arc-sdk/db"
+	"github.com/yourorg/arc-sdk/db/migrations"
 )
 
 var (
 	ErrNotFound = errors.New("store: key not found")
+	// ErrVersionMismatch is returned by CompareAndSet when the key's current
+	// version differs from the expected one.
+	ErrVersionMismatch = errors.New("store: version mismatch")
 )
 
 // KVStore is a simple key-value store interface.
@@ -25,115 +33,601 @@ type KVStore interface {
 	// Get retrieves the value for the given key.
 	// Returns ErrNotFound if the key does not exist.
 	Get(ctx context.Context, key string) ([]byte, error)
-	// Set stores the value for the given key, overwriting any existing value.
+	// Set stores the value for the given key, overwriting any existing value
+	// and clearing any expiry.
 	Set(ctx context.Context, key string, value []byte) error
+	// SetWithTTL stores the value so that it expires after ttl. A ttl <= 0
+	// behaves like Set. Expired keys read as ErrNotFound.
+	SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error
 	// Delete removes the key and its value.
 	Delete(ctx context.Context, key string) error
+	// Purge removes every expired key and returns how many were removed.
+	Purge(ctx context.Context) (int, error)
+	// List returns the live keys starting with prefix in ascending order.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Scan calls fn for each live key starting with prefix, in ascending key
+	// order, until fn returns false.
+	Scan(ctx context.Context, prefix string, fn func(key string, value []byte) bool) error
+	// DeletePrefix removes every key starting with prefix and returns how
+	// many were removed.
+	DeletePrefix(ctx context.Context, prefix string) (int, error)
+	// GetVersioned returns the value and its version. Versions are positive,
+	// increase with every write to the key and are never reused for it, even
+	// after the key is deleted.
+	GetVersioned(ctx context.Context, key string) ([]byte, int64, error)
+	// CompareAndSet stores value only if the key's current version equals
+	// expected (0 meaning the key must not exist), clearing any expiry, and
+	// returns the new version. It returns ErrVersionMismatch otherwise.
+	CompareAndSet(ctx context.Context, key string, expected int64, value []byte) (int64, error)
+	// Txn runs fn atomically: writes made through tx are committed together
+	// if fn returns nil and discarded otherwise. fn must use only tx, not the
+	// store itself.
+	Txn(ctx context.Context, fn func(tx KVTxn) error) error
+	// Watch delivers changes to keys starting with prefix, including changes
+	// made by other processes where the backend can see them. The channel is
+	// closed when ctx is done or the store is closed.
+	Watch(ctx context.Context, prefix string) (<-chan KVEvent, error)
 	// Close releases any resources held by the store (e.g., database connections).
 	Close() error
 }
 
+// KVTxn is the view of a KVStore inside Txn. Reads see the transaction's own
+// pending writes.
+type KVTxn interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte) error
+	SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
 // sqliteStore implements KVStore using a SQLite database table.
 type sqliteStore struct {
 	db *sql.DB
+	// q runs the key-value statements: db itself, or a transaction for the
+	// view handed to Txn.
+	q db.Querier
+	// owned is set when the store opened db itself and must close it.
+	owned bool
+
+	watchInterval time.Duration
+	done          chan struct{}
+	closeOnce     sync.Once
 }
 
-// OpenSQLiteStore opens a SQLite database at the given path and returns a KVStore.
+// OpenSQLiteStore opens the arc database at the given path, applying all arc
+// migrations, and returns a KVStore over its "kv_store" table.
 // If path is empty, db.DefaultDBPath() is used.
-// The store uses a table named "kv_store". The table is created if it does not exist.
 func OpenSQLiteStore(path string) (KVStore, error) {
-	if path == "" {
-		path = db.DefaultDBPath()
+	return OpenSQLiteStoreWithOptions(path, SQLiteStoreOptions{})
+}
+
+// SQLiteStoreOptions configures OpenSQLiteStoreWithOptions.
+type SQLiteStoreOptions struct {
+	// KVOnly gives the file just the kv_store schema instead of the full arc
+	// schema, for a standalone key-value file. The KV migration is still
+	// recorded in schema_migrations, so db.Open can later add the rest.
+	KVOnly bool
+	// DB is passed to db.OpenWithOptions.
+	DB db.Options
+}
+
+// OpenSQLiteStoreWithOptions is OpenSQLiteStore with options. The store owns
+// the handle it opens and closes it on Close.
+func OpenSQLiteStoreWithOptions(path string, opts SQLiteStoreOptions) (KVStore, error) {
+	dbOpts := opts.DB
+	if opts.KVOnly {
+		dbOpts.SkipMigrations = true
 	}
-	// Open the database using arc-sdk/db (applies pragmas and migrations)
-	d, err := db.Open(path)
+	d, err := db.OpenWithOptions(path, dbOpts)
 	if err != nil {
 		return nil, err
 	}
-	// Ensure kv_store table exists
-	if _, err := d.Exec(`
-		CREATE TABLE IF NOT EXISTS kv_store (
-			key TEXT PRIMARY KEY,
-			value TEXT,
-			updated_at INTEGER NOT NULL
-		)
-	`); err != nil {
+	s, err := newSQLiteStore(d)
+	if err != nil {
 		d.Close()
 		return nil, err
 	}
-	return &sqliteStore{db: d}, nil
+	s.owned = true
+	return s, nil
+}
+
+// NewSQLiteStore returns a KVStore over the kv_store table of an already open
+// database, applying the KV migration if it is pending. The caller keeps
+// ownership of d: Close stops the store's watchers but leaves d open.
+func NewSQLiteStore(d *sql.DB) (KVStore, error) {
+	return newSQLiteStore(d)
+}
+
+func newSQLiteStore(d *sql.DB) (*sqliteStore, error) {
+	if err := migrations.RunVersions(d, migrations.KVStoreVersion, migrations.KVVersionSeqVersion); err != nil {
+		return nil, fmt.Errorf("kv_store schema: %w", err)
+	}
+	return &sqliteStore{db: d, q: d, watchInterval: defaultWatchInterval, done: make(chan struct{})}, nil
 }
 
 func (s *sqliteStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, _, err := s.GetVersioned(ctx, key)
+	return value, err
+}
+
+func (s *sqliteStore) GetVersioned(ctx context.Context, key string) ([]byte, int64, error) {
 	var valStr string
-	err := s.db.QueryRowContext(ctx, `
-		SELECT value FROM kv_store WHERE key = ?
-	`, key).Scan(&valStr)
+	var version int64
+	var expiresAt sql.NullInt64
+	err := s.q.QueryRowContext(ctx, `
+		SELECT value, version, expires_at FROM kv_store WHERE key = ?
+	`, key).Scan(&valStr, &version, &expiresAt)
 	if err == sql.ErrNoRows {
-		return nil, ErrNotFound
+		return nil, 0, ErrNotFound
 	}
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return []byte(valStr), nil
+	if now := time.Now().UnixNano(); expiresAt.Valid && expiresAt.Int64 <= now {
+		// Expire lazily; the condition keeps a concurrent Set intact.
+		if _, err := s.q.ExecContext(ctx, `
+			DELETE FROM kv_store WHERE key = ? AND expires_at <= ?
+		`, key, now); err != nil {
+			return nil, 0, err
+		}
+		return nil, 0, ErrNotFound
+	}
+	return []byte(valStr), version, nil
+}
+
+func (s *sqliteStore) expiresAt(ctx context.Context, key string) (time.Time, error) {
+	var expiresAt sql.NullInt64
+	err := s.q.QueryRowContext(ctx, `SELECT expires_at FROM kv_store WHERE key = ?`, key).Scan(&expiresAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, ErrNotFound
+	}
+	if err != nil || !expiresAt.Valid {
+		return time.Time{}, err
+	}
+	return time.Unix(0, expiresAt.Int64), nil
 }
 
 func (s *sqliteStore) Set(ctx context.Context, key string, value []byte) error {
+	return s.SetWithTTL(ctx, key, value, 0)
+}
+
+func (s *sqliteStore) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
 	now := time.Now().UnixNano()
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO kv_store (key, value, updated_at) VALUES (?, ?, ?)
-		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
-	`, key, string(value), now)
-	return err
+	var expiresAt sql.NullInt64
+	if ttl > 0 {
+		expiresAt = sql.NullInt64{Int64: now + int64(ttl), Valid: true}
+	}
+	return db.InTx(ctx, s.q, func(tx *sql.Tx) error {
+		version, err := nextVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO kv_store (key, value, updated_at, expires_at, version) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at,
+				expires_at = excluded.expires_at, version = excluded.version
+		`, key, string(value), now, expiresAt, version)
+		return err
+	})
+}
+
+// nextVersion takes the next number from the store-wide version sequence.
+func nextVersion(ctx context.Context, tx *sql.Tx) (int64, error) {
+	var version int64
+	err := tx.QueryRowContext(ctx, `
+		UPDATE kv_meta SET value = value + 1 WHERE name = 'version_seq' RETURNING value
+	`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("next kv version: %w", err)
+	}
+	return version, nil
+}
+
+func (s *sqliteStore) CompareAndSet(ctx context.Context, key string, expected int64, value []byte) (int64, error) {
+	now := time.Now().UnixNano()
+	var version int64
+	err := db.InTx(ctx, s.q, func(tx *sql.Tx) error {
+		next, err := nextVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if expected == 0 {
+			// Absent, or present but expired.
+			err = tx.QueryRowContext(ctx, `
+				INSERT INTO kv_store (key, value, updated_at, expires_at, version) VALUES (?, ?, ?, NULL, ?)
+				ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at,
+					expires_at = NULL, version = excluded.version
+				WHERE kv_store.expires_at IS NOT NULL AND kv_store.expires_at <= excluded.updated_at
+				RETURNING version
+			`, key, string(value), now, next).Scan(&version)
+		} else {
+			err = tx.QueryRowContext(ctx, `
+				UPDATE kv_store SET value = ?, updated_at = ?, expires_at = NULL, version = ?
+				WHERE key = ? AND version = ? AND (expires_at IS NULL OR expires_at > ?)
+				RETURNING version
+			`, string(value), now, next, key, expected, now).Scan(&version)
+		}
+		if err == sql.ErrNoRows {
+			// Rolling back returns the unused number to the sequence.
+			return ErrVersionMismatch
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
 }
 
 func (s *sqliteStore) Delete(ctx context.Context, key string) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM kv_store WHERE key = ?`, key)
+	_, err := s.q.ExecContext(ctx, `DELETE FROM kv_store WHERE key = ?`, key)
+	return err
+}
+
+func (s *sqliteStore) Purge(ctx context.Context) (int, error) {
+	res, err := s.q.ExecContext(ctx, `
+		DELETE FROM kv_store WHERE expires_at IS NOT NULL AND expires_at <= ?
+	`, time.Now().UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Txn takes the write lock up front so the transaction cannot fail to upgrade
+// midway; fn may be run again if the database stays busy.
+// Txn takes the write lock up front where the handle allows it, so reads in
+// fn cannot go stale under it; on other handles it starts a deferred
+// transaction and relies on the busy retry.
+func (s *sqliteStore) Txn(ctx context.Context, fn func(tx KVTxn) error) error {
+	run := func(ctx context.Context, tx *sql.Tx) error {
+		return fn(&sqliteStore{q: tx})
+	}
+	err := db.WithTxOptions(ctx, s.db, db.TxOptions{Immediate: true, Retries: 3}, run)
+	if errors.Is(err, db.ErrImmediateUnsupported) {
+		err = db.WithTxOptions(ctx, s.db, db.TxOptions{Retries: 3}, run)
+	}
 	return err
 }
 
+// prefixRange returns a WHERE clause selecting keys that start with prefix as
+// an index range scan, rather than a LIKE that would need escaping.
+func prefixRange(prefix string) (string, []any) {
+	if prefix == "" {
+		return "1", nil
+	}
+	if end, ok := prefixEnd(prefix); ok {
+		return "key >= ? AND key < ?", []any{prefix, end}
+	}
+	return "key >= ?", []any{prefix}
+}
+
+// prefixEnd returns the smallest string greater than every string with the
+// given prefix, or false if there is none.
+func prefixEnd(prefix string) (string, bool) {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1]), true
+		}
+	}
+	return "", false
+}
+
+func (s *sqliteStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := s.scan(ctx, prefix, false, func(key string, _ []byte) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys, err
+}
+
+func (s *sqliteStore) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) bool) error {
+	return s.scan(ctx, prefix, true, fn)
+}
+
+func (s *sqliteStore) scan(ctx context.Context, prefix string, withValues bool, fn func(key string, value []byte) bool) error {
+	where, args := prefixRange(prefix)
+	cols := "key, NULL"
+	if withValues {
+		cols = "key, value"
+	}
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT `+cols+` FROM kv_store
+		WHERE `+where+` AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY key
+	`, append(args, time.Now().UnixNano())...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var value sql.NullString
+		if err := rows.Scan(&key, &value); err != nil {
+			return err
+		}
+		if !fn(key, []byte(value.String)) {
+			return nil
+		}
+	}
+	return rows.Err()
+}
+
+func (s *sqliteStore) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	where, args := prefixRange(prefix)
+	res, err := s.q.ExecContext(ctx, `DELETE FROM kv_store WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
 func (s *sqliteStore) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	if !s.owned {
+		return nil
+	}
 	return s.db.Close()
 }
 
 // memoryStore implements KVStore using an in-memory map.
 type memoryStore struct {
-	data map[string][]byte
+	data map[string]memoryEntry
 	mu   sync.RWMutex
+	// seq is the last version handed out, store-wide.
+	seq int64
+
+	hub       watchHub
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// Bounds set by NewLRUStore; recency is nil for unbounded stores.
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	recency    *list.List
+	elems      map[string]*list.Element
+}
+
+type memoryEntry struct {
+	value   []byte
+	version int64
+	// expiresAt is zero for keys that never expire.
+	expiresAt time.Time
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
 }
 
 // NewMemoryStore returns a new in-memory KVStore.
 // It is suitable for use when persistence is not required or unavailable.
 func NewMemoryStore() KVStore {
-	return &memoryStore{data: make(map[string][]byte)}
+	return &memoryStore{data: make(map[string]memoryEntry), done: make(chan struct{})}
 }
 
 func (s *memoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, _, err := s.GetVersioned(ctx, key)
+	return value, err
+}
+
+func (s *memoryStore) GetVersioned(ctx context.Context, key string) ([]byte, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.live(key, time.Now())
+	if !ok {
+		return nil, 0, ErrNotFound
+	}
+	return e.value, e.version, nil
+}
+
+// live returns the unexpired entry for key, dropping it if it has expired;
+// callers hold s.mu for writing.
+func (s *memoryStore) live(key string, now time.Time) (memoryEntry, bool) {
+	e, ok := s.data[key]
+	if ok && e.expired(now) {
+		s.remove(key)
+		return memoryEntry{}, false
+	}
+	if ok {
+		s.touch(key)
+	}
+	return e, ok
+}
+
+func (s *memoryStore) expiresAt(ctx context.Context, key string) (time.Time, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	v, ok := s.data[key]
+	e, ok := s.data[key]
 	if !ok {
-		return nil, ErrNotFound
+		return time.Time{}, ErrNotFound
 	}
-	return v, nil
+	return e.expiresAt, nil
 }
 
 func (s *memoryStore) Set(ctx context.Context, key string, value []byte) error {
+	return s.SetWithTTL(ctx, key, value, 0)
+}
+
+func (s *memoryStore) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.data[key] = value
+	e := memoryEntry{value: value, version: s.nextVersion()}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+	s.put(key, e)
 	return nil
 }
 
+func (s *memoryStore) CompareAndSet(ctx context.Context, key string, expected int64, value []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var current int64
+	if e, ok := s.live(key, time.Now()); ok {
+		current = e.version
+	}
+	if current != expected {
+		return 0, ErrVersionMismatch
+	}
+	e := memoryEntry{value: value, version: s.nextVersion()}
+	s.put(key, e)
+	return e.version, nil
+}
+
+// nextVersion takes the next store-wide version; callers hold s.mu.
+func (s *memoryStore) nextVersion() int64 {
+	s.seq++
+	return s.seq
+}
+
 func (s *memoryStore) Delete(ctx context.Context, key string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.data, key)
+	s.remove(key)
+	return nil
+}
+
+func (s *memoryStore) Purge(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	n := 0
+	for k, e := range s.data {
+		if e.expired(now) {
+			s.remove(k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryStore) List(ctx context.Context, prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys(prefix, time.Now()), nil
+}
+
+// keys returns the sorted live keys with prefix; callers hold s.mu.
+func (s *memoryStore) keys(prefix string, now time.Time) []string {
+	var keys []string
+	for k, e := range s.data {
+		if strings.HasPrefix(k, prefix) && !e.expired(now) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *memoryStore) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) bool) error {
+	// Snapshot under the lock so fn may call back into the store.
+	s.mu.RLock()
+	keys := s.keys(prefix, time.Now())
+	values := make([][]byte, len(keys))
+	for i, k := range keys {
+		values[i] = s.data[k].value
+	}
+	s.mu.RUnlock()
+
+	for i, k := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !fn(k, values[i]) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for k := range s.data {
+		if strings.HasPrefix(k, prefix) {
+			s.remove(k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryStore) Txn(ctx context.Context, fn func(tx KVTxn) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx := &memoryTxn{s: s, pending: make(map[string]*memoryEntry)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	for k, e := range tx.pending {
+		if e == nil {
+			s.remove(k)
+			continue
+		}
+		e.version = s.nextVersion()
+		s.put(k, *e)
+	}
+	return nil
+}
+
+// memoryTxn buffers writes until Txn commits; nil marks a pending delete.
+type memoryTxn struct {
+	s       *memoryStore
+	pending map[string]*memoryEntry
+}
+
+func (t *memoryTxn) Get(ctx context.Context, key string) ([]byte, error) {
+	now := time.Now()
+	if e, ok := t.pending[key]; ok {
+		if e == nil || e.expired(now) {
+			return nil, ErrNotFound
+		}
+		return e.value, nil
+	}
+	e, ok := t.s.live(key, now)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return e.value, nil
+}
+
+func (t *memoryTxn) Set(ctx context.Context, key string, value []byte) error {
+	return t.SetWithTTL(ctx, key, value, 0)
+}
+
+func (t *memoryTxn) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	e := &memoryEntry{value: value}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+	t.pending[key] = e
 	return nil
 }
 
+func (t *memoryTxn) Delete(ctx context.Context, key string) error {
+	t.pending[key] = nil
+	return nil
+}
+
+// Close ends watches and drops the stored values. The store stays usable,
+// empty, so calls racing with Close do not fail.
 func (s *memoryStore) Close() error {
-	s.data = nil
+	s.closeOnce.Do(func() { close(s.done) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.data)
+	s.bytes = 0
+	if s.recency != nil {
+		s.recency.Init()
+		clear(s.elems)
+	}
 	return nil
 }
 
@@ -161,3 +655,68 @@ func SetJSON[T any](ctx context.Context, store KVStore, key string, value T) err
 	}
 	return store.Set(ctx, key, data)
 }
+
+// SetJSONWithTTL marshals value as JSON and stores it under key until ttl elapses.
+func SetJSONWithTTL[T any](ctx context.Context, store KVStore, key string, value T, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return store.SetWithTTL(ctx, key, data, ttl)
+}
+
+// GetOrSetJSON returns the cached JSON value for key, or calls fill, caches
+// its result for ttl and returns it.
+func GetOrSetJSON[T any](ctx context.Context, store KVStore, key string, ttl time.Duration, fill func(context.Context) (T, error)) (T, error) {
+	v, err := GetJSON[T](ctx, store, key)
+	if err == nil || !errors.Is(err, ErrNotFound) {
+		return v, err
+	}
+	if v, err = fill(ctx); err != nil {
+		return v, err
+	}
+	return v, SetJSONWithTTL(ctx, store, key, v, ttl)
+}
+
+// updateJSONAttempts bounds UpdateJSON's retries under contention.
+const updateJSONAttempts = 10
+
+// UpdateJSON performs an atomic read-modify-write of the JSON value at key.
+// fn receives the current value (the zero value if the key is missing) and
+// modifies it in place; the result is written with CompareAndSet, and the
+// whole cycle is retried if another writer got there first.
+func UpdateJSON[T any](ctx context.Context, store KVStore, key string, fn func(v *T) error) (T, error) {
+	var zero T
+	for attempt := 0; attempt < updateJSONAttempts; attempt++ {
+		var v T
+		data, version, err := store.GetVersioned(ctx, key)
+		switch {
+		case errors.Is(err, ErrNotFound):
+		case err != nil:
+			return zero, err
+		default:
+			if err := json.Unmarshal(data, &v); err != nil {
+				return zero, err
+			}
+		}
+		if err := fn(&v); err != nil {
+			return zero, err
+		}
+		out, err := json.Marshal(v)
+		if err != nil {
+			return zero, err
+		}
+		if _, err := store.CompareAndSet(ctx, key, version, out); err == nil {
+			return v, nil
+		} else if !errors.Is(err, ErrVersionMismatch) {
+			return zero, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * time.Millisecond):
+		}
+	}
+	return zero, fmt.Errorf("update %s: %w after %d attempts", key, ErrVersionMismatch, updateJSONAttempts)
+}
@@ -0,0 +1,360 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EncryptionKeySize is the required key length (AES-256).
+const EncryptionKeySize = 32
+
+// encryptedPrefix marks sealed values: arcenc:v1:<key id>:<base64 nonce+ciphertext>.
+const encryptedPrefix = "arcenc:v1:"
+
+// rotateAttempts bounds retries when a value changes while being re-encrypted.
+const rotateAttempts = 5
+
+// ErrDecrypt is returned when a stored value cannot be decrypted, either
+// because it was not written by an EncryptedStore or its key is unknown.
+var ErrDecrypt = errors.New("store: cannot decrypt value")
+
+// errValueChanged makes Rotate retry a value written while re-encrypting it.
+var errValueChanged = errors.New("value changed")
+
+// expiryReader is implemented by stores that can report when a key expires,
+// so Rotate can keep its expiry.
+type expiryReader interface {
+	// expiresAt returns the key's expiry, zero if it never expires, or
+	// ErrNotFound.
+	expiresAt(ctx context.Context, key string) (time.Time, error)
+}
+
+// EncryptedStore is a KVStore wrapper that encrypts values at rest with
+// AES-GCM. Keys (names) are stored in clear and bound to their values, so a
+// ciphertext copied to another key does not decrypt.
+type EncryptedStore struct {
+	kv KVStore
+	// mu guards the keyring, which Rotate changes while the store is in use.
+	mu      sync.RWMutex
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewEncryptedStore wraps kv so values are sealed with key. oldKeys are
+// accepted for decryption only, e.g. while rotating.
+func NewEncryptedStore(kv KVStore, key []byte, oldKeys ...[]byte) (*EncryptedStore, error) {
+	s := &EncryptedStore{kv: kv, aeads: make(map[string]cipher.AEAD)}
+	for _, k := range oldKeys {
+		if _, err := s.addKey(k); err != nil {
+			return nil, err
+		}
+	}
+	id, err := s.addKey(key)
+	if err != nil {
+		return nil, err
+	}
+	s.primary = id
+	return s, nil
+}
+
+// EncryptionKeyID returns the short identifier recorded with values sealed by key.
+func EncryptionKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+func (s *EncryptedStore) addKey(key []byte) (string, error) {
+	if len(key) != EncryptionKeySize {
+		return "", fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	id := EncryptionKeyID(key)
+	s.mu.Lock()
+	s.aeads[id] = aead
+	s.mu.Unlock()
+	return id, nil
+}
+
+func (s *EncryptedStore) primaryKey() (string, cipher.AEAD) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.primary, s.aeads[s.primary]
+}
+
+func (s *EncryptedStore) seal(key string, plaintext []byte) ([]byte, error) {
+	id, aead := s.primaryKey()
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(key))
+	return []byte(encryptedPrefix + id + ":" + base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+func (s *EncryptedStore) open(key string, value []byte) ([]byte, error) {
+	id, payload, ok := strings.Cut(strings.TrimPrefix(string(value), encryptedPrefix), ":")
+	if !ok || !strings.HasPrefix(string(value), encryptedPrefix) {
+		return nil, fmt.Errorf("%w: %s is not encrypted", ErrDecrypt, key)
+	}
+	s.mu.RLock()
+	aead, ok := s.aeads[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s uses unknown key %s", ErrDecrypt, key, id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: %s is malformed", ErrDecrypt, key)
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrDecrypt, key, err)
+	}
+	return plaintext, nil
+}
+
+// sealedKeyID returns the id of the key a stored value was sealed with.
+func sealedKeyID(value []byte) string {
+	id, _, _ := strings.Cut(strings.TrimPrefix(string(value), encryptedPrefix), ":")
+	return id
+}
+
+// Rotate makes newKey the primary key and re-encrypts every value sealed
+// with another key, returning how many were rewritten. It is safe to call
+// while the store is in use. Values keep their remaining expiry over the
+// SQLite, memory and file stores; over other stores, which cannot report
+// expiry, they lose it. The previous keys stay usable for decryption on this
+// handle; once Rotate returns they are no longer needed.
+func (s *EncryptedStore) Rotate(ctx context.Context, newKey []byte) (int, error) {
+	id, err := s.addKey(newKey)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	s.primary = id
+	s.mu.Unlock()
+
+	keys, err := s.kv.List(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, key := range keys {
+		rewritten, err := s.reseal(ctx, key)
+		if err != nil {
+			return n, err
+		}
+		if rewritten {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *EncryptedStore) reseal(ctx context.Context, key string) (bool, error) {
+	for attempt := 0; attempt < rotateAttempts; attempt++ {
+		raw, err := s.kv.Get(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if id, _ := s.primaryKey(); sealedKeyID(raw) == id {
+			return false, nil
+		}
+		var ttl time.Duration
+		if r, ok := s.kv.(expiryReader); ok {
+			expiresAt, err := r.expiresAt(ctx, key)
+			if errors.Is(err, ErrNotFound) {
+				return false, nil
+			}
+			if err != nil {
+				return false, err
+			}
+			if !expiresAt.IsZero() {
+				if ttl = time.Until(expiresAt); ttl <= 0 {
+					return false, nil
+				}
+			}
+		}
+		plaintext, err := s.open(key, raw)
+		if err != nil {
+			return false, err
+		}
+		sealed, err := s.seal(key, plaintext)
+		if err != nil {
+			return false, err
+		}
+		err = s.kv.Txn(ctx, func(tx KVTxn) error {
+			// Sealing never repeats a value, so an unchanged value has not
+			// been rewritten, with another expiry, since it was read.
+			cur, err := tx.Get(ctx, key)
+			if errors.Is(err, ErrNotFound) || err == nil && !bytes.Equal(cur, raw) {
+				return errValueChanged
+			}
+			if err != nil {
+				return err
+			}
+			return tx.SetWithTTL(ctx, key, sealed, ttl)
+		})
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, errValueChanged) {
+			return false, err
+		}
+	}
+	return false, fmt.Errorf("re-encrypt %s: %w", key, ErrVersionMismatch)
+}
+
+func (s *EncryptedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, _, err := s.GetVersioned(ctx, key)
+	return value, err
+}
+
+func (s *EncryptedStore) GetVersioned(ctx context.Context, key string) ([]byte, int64, error) {
+	raw, version, err := s.kv.GetVersioned(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	value, err := s.open(key, raw)
+	return value, version, err
+}
+
+func (s *EncryptedStore) Set(ctx context.Context, key string, value []byte) error {
+	return s.SetWithTTL(ctx, key, value, 0)
+}
+
+func (s *EncryptedStore) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	sealed, err := s.seal(key, value)
+	if err != nil {
+		return err
+	}
+	return s.kv.SetWithTTL(ctx, key, sealed, ttl)
+}
+
+func (s *EncryptedStore) CompareAndSet(ctx context.Context, key string, expected int64, value []byte) (int64, error) {
+	sealed, err := s.seal(key, value)
+	if err != nil {
+		return 0, err
+	}
+	return s.kv.CompareAndSet(ctx, key, expected, sealed)
+}
+
+func (s *EncryptedStore) Delete(ctx context.Context, key string) error {
+	return s.kv.Delete(ctx, key)
+}
+
+func (s *EncryptedStore) Purge(ctx context.Context) (int, error) {
+	return s.kv.Purge(ctx)
+}
+
+func (s *EncryptedStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return s.kv.List(ctx, prefix)
+}
+
+// Scan stops with ErrDecrypt at the first value it cannot decrypt.
+func (s *EncryptedStore) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) bool) error {
+	var openErr error
+	err := s.kv.Scan(ctx, prefix, func(key string, raw []byte) bool {
+		value, err := s.open(key, raw)
+		if err != nil {
+			openErr = err
+			return false
+		}
+		return fn(key, value)
+	})
+	if err != nil {
+		return err
+	}
+	return openErr
+}
+
+func (s *EncryptedStore) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	return s.kv.DeletePrefix(ctx, prefix)
+}
+
+func (s *EncryptedStore) Txn(ctx context.Context, fn func(tx KVTxn) error) error {
+	return s.kv.Txn(ctx, func(tx KVTxn) error {
+		return fn(&encryptedTxn{tx: tx, s: s})
+	})
+}
+
+// Watch decrypts event values; events whose value cannot be decrypted are
+// delivered with a nil Value.
+func (s *EncryptedStore) Watch(ctx context.Context, prefix string) (<-chan KVEvent, error) {
+	events, err := s.kv.Watch(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan KVEvent)
+	go func() {
+		defer close(out)
+		for ev := range events {
+			if !ev.Deleted {
+				ev.Value, _ = s.open(ev.Key, ev.Value)
+			}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (s *EncryptedStore) Close() error {
+	return s.kv.Close()
+}
+
+type encryptedTxn struct {
+	tx KVTxn
+	s  *EncryptedStore
+}
+
+func (t *encryptedTxn) Get(ctx context.Context, key string) ([]byte, error) {
+	raw, err := t.tx.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return t.s.open(key, raw)
+}
+
+func (t *encryptedTxn) Set(ctx context.Context, key string, value []byte) error {
+	return t.SetWithTTL(ctx, key, value, 0)
+}
+
+func (t *encryptedTxn) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	sealed, err := t.s.seal(key, value)
+	if err != nil {
+		return err
+	}
+	return t.tx.SetWithTTL(ctx, key, sealed, ttl)
+}
+
+func (t *encryptedTxn) Delete(ctx context.Context, key string) error {
+	return t.tx.Delete(ctx, key)
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// fileDocument is the on-disk format of a file store.
+type fileDocument struct {
+	Version int                  `json:"version"`
+	Entries map[string]fileEntry `json:"entries"`
+	// Seq is the last entry version handed out, so versions of deleted
+	// keys are not reused.
+	Seq int64 `json:"seq,omitempty"`
+}
+
+type fileEntry struct {
+	// Value holds UTF-8 values; anything else is stored base64 in Base64.
+	Value   *string `json:"value,omitempty"`
+	Base64  []byte  `json:"b64,omitempty"`
+	Version int64   `json:"version"`
+	// ExpiresAt is Unix nanoseconds, 0 for no expiry.
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+}
+
+// fileStore implements KVStore as a single JSON document. Every operation
+// takes an advisory lock on a sibling ".lock" file and reloads the document
+// if another process changed it; writes replace the file atomically.
+type fileStore struct {
+	path string
+	mu   sync.Mutex
+	lock *os.File
+	mem  *memoryStore
+	// raw is the document as last loaded or written.
+	raw []byte
+}
+
+// OpenFileStore opens (or creates on first write) a KVStore backed by the JSON
+// file at path. It suits small tools that do not want SQLite; each write
+// rewrites the whole file.
+func OpenFileStore(path string) (KVStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &fileStore{path: path, lock: lock, mem: NewMemoryStore().(*memoryStore)}
+	if err := s.do(false, func(*memoryStore) error { return nil }); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// do runs fn against the current document, saving it afterwards if write is
+// set and fn succeeded. A change that cannot be saved is discarded.
+func (s *fileStore) do(write bool, fn func(m *memoryStore) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := lockFile(s.lock, write); err != nil {
+		return fmt.Errorf("lock %s: %w", s.path, err)
+	}
+	defer unlockFile(s.lock)
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if err := fn(s.mem); err != nil || !write {
+		return err
+	}
+	if err := s.save(); err != nil {
+		// Reload so reads match the file rather than the unsaved change.
+		s.raw = nil
+		if lerr := s.load(); lerr != nil {
+			return errors.Join(err, lerr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *fileStore) load() error {
+	raw, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if s.raw != nil && bytes.Equal(raw, s.raw) {
+		return nil
+	}
+
+	entries := make(map[string]memoryEntry)
+	var seq int64
+	if len(raw) > 0 {
+		var doc fileDocument
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return fmt.Errorf("parse %s: %w", s.path, err)
+		}
+		seq = doc.Seq
+		for k, fe := range doc.Entries {
+			e := memoryEntry{value: fe.Base64, version: fe.Version}
+			if fe.Value != nil {
+				e.value = []byte(*fe.Value)
+			}
+			if fe.ExpiresAt != 0 {
+				e.expiresAt = time.Unix(0, fe.ExpiresAt)
+			}
+			entries[k] = e
+			seq = max(seq, e.version)
+		}
+	}
+	s.mem.replace(entries, seq)
+	s.raw = raw
+	if s.raw == nil {
+		s.raw = []byte{}
+	}
+	return nil
+}
+
+func (s *fileStore) save() error {
+	doc := fileDocument{Version: 1, Entries: make(map[string]fileEntry)}
+	s.mem.mu.RLock()
+	doc.Seq = s.mem.seq
+	for k, e := range s.mem.data {
+		fe := fileEntry{Version: e.version}
+		if utf8.Valid(e.value) {
+			v := string(e.value)
+			fe.Value = &v
+		} else {
+			fe.Base64 = e.value
+		}
+		if !e.expiresAt.IsZero() {
+			fe.ExpiresAt = e.expiresAt.UnixNano()
+		}
+		doc.Entries[k] = fe
+	}
+	s.mem.mu.RUnlock()
+
+	raw, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	s.raw = raw
+	return nil
+}
+
+// replace swaps in entries loaded from elsewhere, along with their version
+// sequence, reporting the differences to watchers.
+func (s *memoryStore) replace(entries map[string]memoryEntry, seq int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq = seq
+	for k := range s.data {
+		if _, ok := entries[k]; !ok {
+			s.remove(k)
+		}
+	}
+	for k, e := range entries {
+		if old, ok := s.data[k]; ok && old.version == e.version && bytes.Equal(old.value, e.value) {
+			s.data[k] = e
+			continue
+		}
+		s.put(k, e)
+	}
+}
+
+func (s *fileStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, _, err := s.GetVersioned(ctx, key)
+	return value, err
+}
+
+func (s *fileStore) GetVersioned(ctx context.Context, key string) (value []byte, version int64, err error) {
+	err = s.do(false, func(m *memoryStore) error {
+		value, version, err = m.GetVersioned(ctx, key)
+		return err
+	})
+	return value, version, err
+}
+
+func (s *fileStore) expiresAt(ctx context.Context, key string) (expiresAt time.Time, err error) {
+	err = s.do(false, func(m *memoryStore) error {
+		expiresAt, err = m.expiresAt(ctx, key)
+		return err
+	})
+	return expiresAt, err
+}
+
+func (s *fileStore) Set(ctx context.Context, key string, value []byte) error {
+	return s.SetWithTTL(ctx, key, value, 0)
+}
+
+func (s *fileStore) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.do(true, func(m *memoryStore) error {
+		return m.SetWithTTL(ctx, key, value, ttl)
+	})
+}
+
+func (s *fileStore) CompareAndSet(ctx context.Context, key string, expected int64, value []byte) (version int64, err error) {
+	err = s.do(true, func(m *memoryStore) error {
+		version, err = m.CompareAndSet(ctx, key, expected, value)
+		return err
+	})
+	return version, err
+}
+
+func (s *fileStore) Delete(ctx context.Context, key string) error {
+	return s.do(true, func(m *memoryStore) error {
+		return m.Delete(ctx, key)
+	})
+}
+
+func (s *fileStore) Purge(ctx context.Context) (n int, err error) {
+	err = s.do(true, func(m *memoryStore) error {
+		n, err = m.Purge(ctx)
+		return err
+	})
+	return n, err
+}
+
+func (s *fileStore) List(ctx context.Context, prefix string) (keys []string, err error) {
+	err = s.do(false, func(m *memoryStore) error {
+		keys, err = m.List(ctx, prefix)
+		return err
+	})
+	return keys, err
+}
+
+func (s *fileStore) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) bool) error {
+	// Collect first so fn runs without the file lock held.
+	var keys []string
+	var values [][]byte
+	err := s.do(false, func(m *memoryStore) error {
+		return m.Scan(ctx, prefix, func(key string, value []byte) bool {
+			keys = append(keys, key)
+			values = append(values, value)
+			return true
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for i, k := range keys {
+		if !fn(k, values[i]) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *fileStore) DeletePrefix(ctx context.Context, prefix string) (n int, err error) {
+	err = s.do(true, func(m *memoryStore) error {
+		n, err = m.DeletePrefix(ctx, prefix)
+		return err
+	})
+	return n, err
+}
+
+func (s *fileStore) Txn(ctx context.Context, fn func(tx KVTxn) error) error {
+	return s.do(true, func(m *memoryStore) error {
+		return m.Txn(ctx, fn)
+	})
+}
+
+// Watch reports in-process writes immediately and polls the file for writes
+// by other processes.
+func (s *fileStore) Watch(ctx context.Context, prefix string) (<-chan KVEvent, error) {
+	events, err := s.mem.Watch(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		ticker := time.NewTicker(defaultWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.mem.done:
+				return
+			case <-ticker.C:
+				if err := s.do(false, func(*memoryStore) error { return nil }); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (s *fileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mem.Close()
+	return s.lock.Close()
+}
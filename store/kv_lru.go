@@ -0,0 +1,77 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import "container/list"
+
+// LRUOptions bounds an LRU store. Zero disables a limit.
+type LRUOptions struct {
+	// MaxEntries caps the number of keys.
+	MaxEntries int
+	// MaxBytes caps the total size of keys plus values.
+	MaxBytes int64
+}
+
+// NewLRUStore returns an in-memory KVStore that evicts the least recently
+// used keys once either bound in opts is exceeded. Evictions are reported to
+// watchers as deletions. A single value larger than MaxBytes is not retained.
+func NewLRUStore(opts LRUOptions) KVStore {
+	s := NewMemoryStore().(*memoryStore)
+	s.maxEntries = opts.MaxEntries
+	s.maxBytes = opts.MaxBytes
+	s.recency = list.New()
+	s.elems = make(map[string]*list.Element)
+	return s
+}
+
+func entrySize(key string, e memoryEntry) int64 {
+	return int64(len(key) + len(e.value))
+}
+
+// put stores e under key, marks it most recently used and evicts as needed;
+// callers hold s.mu for writing.
+func (s *memoryStore) put(key string, e memoryEntry) {
+	if old, ok := s.data[key]; ok {
+		s.bytes -= entrySize(key, old)
+	}
+	s.data[key] = e
+	s.bytes += entrySize(key, e)
+	s.hub.publish(KVEvent{Key: key, Value: e.value})
+
+	if s.recency == nil {
+		return
+	}
+	s.touch(key)
+	for s.recency.Len() > 0 &&
+		((s.maxEntries > 0 && len(s.data) > s.maxEntries) || (s.maxBytes > 0 && s.bytes > s.maxBytes)) {
+		s.remove(s.recency.Back().Value.(string))
+	}
+}
+
+// remove deletes key if present; callers hold s.mu for writing.
+func (s *memoryStore) remove(key string) {
+	e, ok := s.data[key]
+	if !ok {
+		return
+	}
+	delete(s.data, key)
+	s.bytes -= entrySize(key, e)
+	if el, ok := s.elems[key]; ok {
+		s.recency.Remove(el)
+		delete(s.elems, key)
+	}
+	s.hub.publish(KVEvent{Key: key, Deleted: true})
+}
+
+// touch marks key most recently used; callers hold s.mu for writing.
+func (s *memoryStore) touch(key string) {
+	if s.recency == nil {
+		return
+	}
+	if el, ok := s.elems[key]; ok {
+		s.recency.MoveToFront(el)
+		return
+	}
+	s.elems[key] = s.recency.PushFront(key)
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// namespacedStore prefixes every key with "<namespace>:".
+type namespacedStore struct {
+	kv     KVStore
+	prefix string
+}
+
+// Namespaced returns a view of kv whose keys are transparently prefixed with
+// "<namespace>:", so modules sharing one store cannot clobber each other.
+// Keys returned by List and Scan have the prefix removed. Closing the view
+// does not close kv.
+func Namespaced(kv KVStore, namespace string) KVStore {
+	return &namespacedStore{kv: kv, prefix: namespace + ":"}
+}
+
+func (s *namespacedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.kv.Get(ctx, s.prefix+key)
+}
+
+func (s *namespacedStore) Set(ctx context.Context, key string, value []byte) error {
+	return s.kv.Set(ctx, s.prefix+key, value)
+}
+
+func (s *namespacedStore) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.kv.SetWithTTL(ctx, s.prefix+key, value, ttl)
+}
+
+func (s *namespacedStore) Delete(ctx context.Context, key string) error {
+	return s.kv.Delete(ctx, s.prefix+key)
+}
+
+// Purge purges expired keys in the whole underlying store.
+func (s *namespacedStore) Purge(ctx context.Context) (int, error) {
+	return s.kv.Purge(ctx)
+}
+
+func (s *namespacedStore) List(ctx context.Context, prefix string) ([]string, error) {
+	keys, err := s.kv.List(ctx, s.prefix+prefix)
+	for i, k := range keys {
+		keys[i] = strings.TrimPrefix(k, s.prefix)
+	}
+	return keys, err
+}
+
+func (s *namespacedStore) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) bool) error {
+	return s.kv.Scan(ctx, s.prefix+prefix, func(key string, value []byte) bool {
+		return fn(strings.TrimPrefix(key, s.prefix), value)
+	})
+}
+
+func (s *namespacedStore) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	return s.kv.DeletePrefix(ctx, s.prefix+prefix)
+}
+
+func (s *namespacedStore) GetVersioned(ctx context.Context, key string) ([]byte, int64, error) {
+	return s.kv.GetVersioned(ctx, s.prefix+key)
+}
+
+func (s *namespacedStore) CompareAndSet(ctx context.Context, key string, expected int64, value []byte) (int64, error) {
+	return s.kv.CompareAndSet(ctx, s.prefix+key, expected, value)
+}
+
+func (s *namespacedStore) Txn(ctx context.Context, fn func(tx KVTxn) error) error {
+	return s.kv.Txn(ctx, func(tx KVTxn) error {
+		return fn(&namespacedTxn{tx: tx, prefix: s.prefix})
+	})
+}
+
+func (s *namespacedStore) Watch(ctx context.Context, prefix string) (<-chan KVEvent, error) {
+	events, err := s.kv.Watch(ctx, s.prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan KVEvent)
+	go func() {
+		defer close(out)
+		for ev := range events {
+			ev.Key = strings.TrimPrefix(ev.Key, s.prefix)
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (s *namespacedStore) Close() error {
+	return nil
+}
+
+type namespacedTxn struct {
+	tx     KVTxn
+	prefix string
+}
+
+func (t *namespacedTxn) Get(ctx context.Context, key string) ([]byte, error) {
+	return t.tx.Get(ctx, t.prefix+key)
+}
+
+func (t *namespacedTxn) Set(ctx context.Context, key string, value []byte) error {
+	return t.tx.Set(ctx, t.prefix+key, value)
+}
+
+func (t *namespacedTxn) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return t.tx.SetWithTTL(ctx, t.prefix+key, value, ttl)
+}
+
+func (t *namespacedTxn) Delete(ctx context.Context, key string) error {
+	return t.tx.Delete(ctx, t.prefix+key)
+}
@@ -5,9 +5,15 @@ package store
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/yourorg/arc-sdk/db"
 )
 
 func TestSQLiteStore(t *testing.T) {
@@ -145,3 +151,671 @@ func TestGracefulDegradation(t *testing.T) {
 		t.Fatalf("MemoryStore Get returned %q, want %q", got, "ok")
 	}
 }
+
+func TestTTLExpiry(t *testing.T) {
+	ctx := context.Background()
+	sqliteKV, err := OpenSQLiteStore(t.TempDir() + "/ttl.db")
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore: %v", err)
+	}
+	defer sqliteKV.Close()
+
+	for name, kv := range map[string]KVStore{"sqlite": sqliteKV, "memory": NewMemoryStore()} {
+		t.Run(name, func(t *testing.T) {
+			if err := kv.SetWithTTL(ctx, "short", []byte("a"), 20*time.Millisecond); err != nil {
+				t.Fatalf("SetWithTTL: %v", err)
+			}
+			if err := kv.SetWithTTL(ctx, "purged", []byte("b"), 20*time.Millisecond); err != nil {
+				t.Fatalf("SetWithTTL: %v", err)
+			}
+			if err := kv.SetWithTTL(ctx, "long", []byte("c"), time.Hour); err != nil {
+				t.Fatalf("SetWithTTL: %v", err)
+			}
+			if got, err := kv.Get(ctx, "short"); err != nil || string(got) != "a" {
+				t.Fatalf("Get before expiry = %q, %v", got, err)
+			}
+
+			time.Sleep(30 * time.Millisecond)
+			if _, err := kv.Get(ctx, "short"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Get after expiry: expected ErrNotFound, got %v", err)
+			}
+			n, err := kv.Purge(ctx)
+			if err != nil {
+				t.Fatalf("Purge: %v", err)
+			}
+			if n != 1 {
+				t.Fatalf("Purge removed %d keys, want 1", n)
+			}
+			if _, err := kv.Get(ctx, "long"); err != nil {
+				t.Fatalf("Get long-lived key: %v", err)
+			}
+
+			calls := 0
+			fill := func(context.Context) (int, error) { calls++; return 42, nil }
+			for i := 0; i < 2; i++ {
+				v, err := GetOrSetJSON(ctx, kv, "cached", time.Hour, fill)
+				if err != nil || v != 42 {
+					t.Fatalf("GetOrSetJSON = %d, %v", v, err)
+				}
+			}
+			if calls != 1 {
+				t.Fatalf("fill called %d times, want 1", calls)
+			}
+		})
+	}
+}
+
+func TestPrefixListingAndNamespaces(t *testing.T) {
+	ctx := context.Background()
+	sqliteKV, err := OpenSQLiteStore(t.TempDir() + "/prefix.db")
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore: %v", err)
+	}
+	defer sqliteKV.Close()
+
+	for name, kv := range map[string]KVStore{"sqlite": sqliteKV, "memory": NewMemoryStore()} {
+		t.Run(name, func(t *testing.T) {
+			alpha := Namespaced(kv, "alpha")
+			beta := Namespaced(kv, "beta")
+			for _, k := range []string{"b", "a", "c/1"} {
+				if err := alpha.Set(ctx, k, []byte("alpha-"+k)); err != nil {
+					t.Fatalf("Set: %v", err)
+				}
+			}
+			if err := beta.Set(ctx, "a", []byte("beta-a")); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			if err := kv.Set(ctx, "alphabet", []byte("x")); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+
+			keys, err := alpha.List(ctx, "")
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if strings.Join(keys, ",") != "a,b,c/1" {
+				t.Fatalf("List = %v, want [a b c/1]", keys)
+			}
+			if got, err := beta.Get(ctx, "a"); err != nil || string(got) != "beta-a" {
+				t.Fatalf("beta Get = %q, %v", got, err)
+			}
+
+			var seen []string
+			if err := kv.Scan(ctx, "alpha:", func(key string, value []byte) bool {
+				seen = append(seen, key+"="+string(value))
+				return len(seen) < 2
+			}); err != nil {
+				t.Fatalf("Scan: %v", err)
+			}
+			if strings.Join(seen, ",") != "alpha:a=alpha-a,alpha:b=alpha-b" {
+				t.Fatalf("Scan = %v", seen)
+			}
+
+			n, err := alpha.DeletePrefix(ctx, "")
+			if err != nil || n != 3 {
+				t.Fatalf("DeletePrefix = %d, %v; want 3", n, err)
+			}
+			if keys, _ := kv.List(ctx, ""); strings.Join(keys, ",") != "alphabet,beta:a" {
+				t.Fatalf("remaining keys = %v", keys)
+			}
+		})
+	}
+}
+
+func TestCompareAndSetAndUpdateJSON(t *testing.T) {
+	ctx := context.Background()
+	sqliteKV, err := OpenSQLiteStore(t.TempDir() + "/cas.db")
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore: %v", err)
+	}
+	defer sqliteKV.Close()
+
+	for name, kv := range map[string]KVStore{"sqlite": sqliteKV, "memory": NewMemoryStore()} {
+		t.Run(name, func(t *testing.T) {
+			v1, err := kv.CompareAndSet(ctx, "cas", 0, []byte("one"))
+			if err != nil || v1 != 1 {
+				t.Fatalf("CompareAndSet create = %d, %v", v1, err)
+			}
+			if _, err := kv.CompareAndSet(ctx, "cas", 0, []byte("again")); !errors.Is(err, ErrVersionMismatch) {
+				t.Fatalf("create over existing: expected ErrVersionMismatch, got %v", err)
+			}
+			if err := kv.Set(ctx, "cas", []byte("two")); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			if _, err := kv.CompareAndSet(ctx, "cas", v1, []byte("stale")); !errors.Is(err, ErrVersionMismatch) {
+				t.Fatalf("stale CompareAndSet: expected ErrVersionMismatch, got %v", err)
+			}
+			value, version, err := kv.GetVersioned(ctx, "cas")
+			if err != nil || string(value) != "two" || version != 2 {
+				t.Fatalf("GetVersioned = %q, %d, %v", value, version, err)
+			}
+
+			type counter struct{ N int }
+			const workers, increments = 4, 5
+			errs := make(chan error, workers)
+			for w := 0; w < workers; w++ {
+				go func() {
+					for i := 0; i < increments; i++ {
+						if _, err := UpdateJSON(ctx, kv, "counter", func(c *counter) error {
+							c.N++
+							return nil
+						}); err != nil {
+							errs <- err
+							return
+						}
+					}
+					errs <- nil
+				}()
+			}
+			for w := 0; w < workers; w++ {
+				if err := <-errs; err != nil {
+					t.Fatalf("UpdateJSON: %v", err)
+				}
+			}
+			got, err := GetJSON[counter](ctx, kv, "counter")
+			if err != nil || got.N != workers*increments {
+				t.Fatalf("counter = %d, %v; want %d", got.N, err, workers*increments)
+			}
+		})
+	}
+}
+
+func TestTxn(t *testing.T) {
+	ctx := context.Background()
+	sqliteKV, err := OpenSQLiteStore(t.TempDir() + "/txn.db")
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore: %v", err)
+	}
+	defer sqliteKV.Close()
+	// A handle opened without db.OpenWithOptions cannot begin immediately.
+	plain, err := sql.Open("sqlite", t.TempDir()+"/plain.db")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer plain.Close()
+	plainKV, err := NewSQLiteStore(plain)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+
+	for name, kv := range map[string]KVStore{"sqlite": sqliteKV, "plain sqlite": plainKV, "memory": NewMemoryStore()} {
+		t.Run(name, func(t *testing.T) {
+			if err := kv.Set(ctx, "index", []byte("a")); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+
+			err := kv.Txn(ctx, func(tx KVTxn) error {
+				if err := tx.Set(ctx, "entry:b", []byte("B")); err != nil {
+					return err
+				}
+				if err := tx.Set(ctx, "index", []byte("a,b")); err != nil {
+					return err
+				}
+				if got, err := tx.Get(ctx, "index"); err != nil || string(got) != "a,b" {
+					t.Errorf("read inside txn = %q, %v; want pending write", got, err)
+				}
+				return tx.Delete(ctx, "entry:b")
+			})
+			if err != nil {
+				t.Fatalf("Txn: %v", err)
+			}
+			if got, _ := kv.Get(ctx, "index"); string(got) != "a,b" {
+				t.Fatalf("index after commit = %q", got)
+			}
+			if _, err := kv.Get(ctx, "entry:b"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("deleted entry: expected ErrNotFound, got %v", err)
+			}
+
+			boom := errors.New("boom")
+			err = kv.Txn(ctx, func(tx KVTxn) error {
+				if err := tx.Set(ctx, "index", []byte("lost")); err != nil {
+					return err
+				}
+				return boom
+			})
+			if !errors.Is(err, boom) {
+				t.Fatalf("Txn error = %v, want boom", err)
+			}
+			if got, _ := kv.Get(ctx, "index"); string(got) != "a,b" {
+				t.Fatalf("index after rollback = %q", got)
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	next := func(t *testing.T, events <-chan KVEvent) KVEvent {
+		t.Helper()
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatalf("watch channel closed early")
+			}
+			return ev
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for event")
+		}
+		return KVEvent{}
+	}
+
+	t.Run("memory", func(t *testing.T) {
+		kv := NewMemoryStore()
+		events, err := kv.Watch(ctx, "cfg:")
+		if err != nil {
+			t.Fatalf("Watch: %v", err)
+		}
+		kv.Set(ctx, "other", []byte("x"))
+		kv.Set(ctx, "cfg:theme", []byte("dark"))
+		if ev := next(t, events); ev.Key != "cfg:theme" || string(ev.Value) != "dark" {
+			t.Fatalf("event = %+v", ev)
+		}
+		kv.Delete(ctx, "cfg:theme")
+		if ev := next(t, events); ev.Key != "cfg:theme" || !ev.Deleted {
+			t.Fatalf("event = %+v, want delete", ev)
+		}
+		kv.Close()
+		if _, ok := <-events; ok {
+			t.Fatalf("expected channel to close with the store")
+		}
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		path := t.TempDir() + "/watch.db"
+		watched, err := OpenSQLiteStore(path)
+		if err != nil {
+			t.Fatalf("OpenSQLiteStore: %v", err)
+		}
+		defer watched.Close()
+		watched.(*sqliteStore).watchInterval = 10 * time.Millisecond
+		// A second handle stands in for another arc process.
+		other, err := OpenSQLiteStore(path)
+		if err != nil {
+			t.Fatalf("OpenSQLiteStore: %v", err)
+		}
+		defer other.Close()
+
+		events, err := Namespaced(watched, "cfg").Watch(ctx, "")
+		if err != nil {
+			t.Fatalf("Watch: %v", err)
+		}
+		if err := other.Set(ctx, "cfg:theme", []byte("light")); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if ev := next(t, events); ev.Key != "theme" || string(ev.Value) != "light" {
+			t.Fatalf("event = %+v", ev)
+		}
+		if err := other.Delete(ctx, "cfg:theme"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if ev := next(t, events); ev.Key != "theme" || !ev.Deleted {
+			t.Fatalf("event = %+v, want delete", ev)
+		}
+	})
+
+	// A single-connection writer over a shared-cache memory database: the
+	// watch must neither starve other calls nor miss their writes.
+	t.Run("pool writer", func(t *testing.T) {
+		pool, err := db.OpenPool(":memory:", db.Options{})
+		if err != nil {
+			t.Fatalf("OpenPool: %v", err)
+		}
+		defer pool.Close()
+		kv, err := NewSQLiteStore(pool.Writer)
+		if err != nil {
+			t.Fatalf("NewSQLiteStore: %v", err)
+		}
+		defer kv.Close()
+		kv.(*sqliteStore).watchInterval = 10 * time.Millisecond
+
+		events, err := kv.Watch(ctx, "cfg:")
+		if err != nil {
+			t.Fatalf("Watch: %v", err)
+		}
+		if err := kv.Set(ctx, "cfg:theme", []byte("dark")); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if ev := next(t, events); ev.Key != "cfg:theme" || string(ev.Value) != "dark" {
+			t.Fatalf("event = %+v", ev)
+		}
+		if err := kv.Delete(ctx, "cfg:theme"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if ev := next(t, events); ev.Key != "cfg:theme" || !ev.Deleted {
+			t.Fatalf("event = %+v, want delete", ev)
+		}
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/state/kv.json"
+	a, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	defer a.Close()
+	// A second handle stands in for another process sharing the file.
+	b, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	defer b.Close()
+
+	binary := []byte{0xff, 0x00, 0xfe}
+	if err := a.Set(ctx, "tool:config", []byte(`{"verbose":true}`)); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := a.Set(ctx, "tool:blob", binary); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, err := b.Get(ctx, "tool:blob"); err != nil || string(got) != string(binary) {
+		t.Fatalf("Get from other handle = %v, %v", got, err)
+	}
+
+	_, version, err := b.GetVersioned(ctx, "tool:config")
+	if err != nil {
+		t.Fatalf("GetVersioned: %v", err)
+	}
+	if err := a.Set(ctx, "tool:config", []byte(`{}`)); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, err := b.CompareAndSet(ctx, "tool:config", version, []byte(`{"stale":true}`)); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("CompareAndSet across handles: expected ErrVersionMismatch, got %v", err)
+	}
+
+	if err := b.Txn(ctx, func(tx KVTxn) error {
+		if err := tx.Delete(ctx, "tool:blob"); err != nil {
+			return err
+		}
+		return tx.Set(ctx, "tool:count", []byte("1"))
+	}); err != nil {
+		t.Fatalf("Txn: %v", err)
+	}
+	keys, err := a.List(ctx, "tool:")
+	if err != nil || strings.Join(keys, ",") != "tool:config,tool:count" {
+		t.Fatalf("List = %v, %v", keys, err)
+	}
+
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	if got, err := reopened.Get(ctx, "tool:count"); err != nil || string(got) != "1" {
+		t.Fatalf("Get after reopen = %q, %v", got, err)
+	}
+}
+
+func TestLRUStore(t *testing.T) {
+	ctx := context.Background()
+	kv := NewLRUStore(LRUOptions{MaxEntries: 2})
+	kv.Set(ctx, "a", []byte("1"))
+	kv.Set(ctx, "b", []byte("2"))
+	kv.Get(ctx, "a") // b is now least recently used
+	kv.Set(ctx, "c", []byte("3"))
+	if _, err := kv.Get(ctx, "b"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected b to be evicted, got %v", err)
+	}
+	if keys, _ := kv.List(ctx, ""); strings.Join(keys, ",") != "a,c" {
+		t.Fatalf("keys = %v, want [a c]", keys)
+	}
+
+	sized := NewLRUStore(LRUOptions{MaxBytes: 10})
+	sized.Set(ctx, "k1", []byte("1234")) // 6 bytes
+	sized.Set(ctx, "k2", []byte("1234")) // 12 bytes: evicts k1
+	if _, err := sized.Get(ctx, "k1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected k1 to be evicted by size, got %v", err)
+	}
+	if got, err := sized.Get(ctx, "k2"); err != nil || string(got) != "1234" {
+		t.Fatalf("Get k2 = %q, %v", got, err)
+	}
+}
+
+func TestTieredStore(t *testing.T) {
+	ctx := context.Background()
+	front := NewLRUStore(LRUOptions{MaxEntries: 10})
+	back := NewMemoryStore()
+	kv, err := Tiered(front, back, TieredOptions{})
+	if err != nil {
+		t.Fatalf("Tiered: %v", err)
+	}
+	defer kv.Close()
+
+	if err := kv.Set(ctx, "mod:state", []byte("v1")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, err := kv.Get(ctx, "mod:state"); err != nil || string(got) != "v1" {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+	if got, err := front.Get(ctx, "mod:state"); err != nil || string(got) != "v1" {
+		t.Fatalf("front not filled: %q, %v", got, err)
+	}
+
+	// A write that bypasses the tiered store still invalidates the cache.
+	if err := back.Set(ctx, "mod:state", []byte("v2")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got, err := kv.Get(ctx, "mod:state")
+		if err == nil && string(got) == "v2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Get after backing write = %q, %v; want v2", got, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := kv.Delete(ctx, "mod:state"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := kv.Get(ctx, "mod:state"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: expected ErrNotFound, got %v", err)
+	}
+
+	// Closing stops invalidation before closing front, which stays usable.
+	if err := kv.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := front.Set(ctx, "after", []byte("x")); err != nil {
+		t.Fatalf("Set after Close: %v", err)
+	}
+
+	if _, err := Tiered(NewMemoryStore(), unwatchableStore{NewMemoryStore()}, TieredOptions{}); err == nil {
+		t.Fatalf("Tiered over an unwatchable store succeeded")
+	}
+}
+
+// unwatchableStore is a KVStore whose Watch always fails.
+type unwatchableStore struct{ KVStore }
+
+func (unwatchableStore) Watch(context.Context, string) (<-chan KVEvent, error) {
+	return nil, errors.New("watch unsupported")
+}
+
+func TestEncryptedStore(t *testing.T) {
+	ctx := context.Background()
+	key1 := []byte(strings.Repeat("k", EncryptionKeySize))
+	key2 := []byte(strings.Repeat("n", EncryptionKeySize))
+	raw := NewMemoryStore()
+	enc, err := NewEncryptedStore(raw, key1)
+	if err != nil {
+		t.Fatalf("NewEncryptedStore: %v", err)
+	}
+
+	if err := enc.Set(ctx, "token", []byte("hunter2")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, err := enc.Get(ctx, "token"); err != nil || string(got) != "hunter2" {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+	sealed, _ := raw.Get(ctx, "token")
+	if strings.Contains(string(sealed), "hunter2") || !strings.HasPrefix(string(sealed), encryptedPrefix) {
+		t.Fatalf("value stored in clear: %q", sealed)
+	}
+
+	// Ciphertext is bound to its key and to the encryption key.
+	raw.Set(ctx, "copy", sealed)
+	if _, err := enc.Get(ctx, "copy"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for moved ciphertext, got %v", err)
+	}
+	raw.Delete(ctx, "copy")
+	other, _ := NewEncryptedStore(raw, key2)
+	if _, err := other.Get(ctx, "token"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt with wrong key, got %v", err)
+	}
+
+	if err := enc.SetWithTTL(ctx, "session", []byte("s1"), time.Hour); err != nil {
+		t.Fatalf("SetWithTTL: %v", err)
+	}
+	// Rotation is safe while the store is in use.
+	stop := make(chan struct{})
+	busy := make(chan struct{})
+	go func() {
+		defer close(busy)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				enc.Set(ctx, "busy", []byte("x"))
+				enc.Get(ctx, "token")
+			}
+		}
+	}()
+	n, err := enc.Rotate(ctx, key2)
+	close(stop)
+	<-busy
+	if err != nil || n < 2 {
+		t.Fatalf("Rotate = %d, %v", n, err)
+	}
+	if got, err := other.Get(ctx, "token"); err != nil || string(got) != "hunter2" {
+		t.Fatalf("Get after rotation = %q, %v", got, err)
+	}
+	if got, err := other.Get(ctx, "session"); err != nil || string(got) != "s1" {
+		t.Fatalf("Get session after rotation = %q, %v", got, err)
+	}
+	if exp, err := raw.(*memoryStore).expiresAt(ctx, "session"); err != nil || time.Until(exp) < 59*time.Minute {
+		t.Fatalf("session expiry after rotation = %v, %v", exp, err)
+	}
+	if n, _ := enc.Rotate(ctx, key2); n != 0 {
+		t.Fatalf("second Rotate rewrote %d values", n)
+	}
+}
+
+func TestSQLiteStoreSharedAndStandalone(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// A legacy kv_store created before the KV migration existed.
+	legacy, err := db.OpenWithOptions(dir+"/legacy.db", db.Options{SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("open legacy: %v", err)
+	}
+	defer legacy.Close()
+	if _, err := legacy.Exec(`CREATE TABLE kv_store (key TEXT PRIMARY KEY, value TEXT, updated_at INTEGER NOT NULL)`); err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+	legacy.Exec(`INSERT INTO kv_store(key, value, updated_at) VALUES('old', 'v', 1)`)
+
+	shared, err := NewSQLiteStore(legacy)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	if got, version, err := shared.GetVersioned(ctx, "old"); err != nil || string(got) != "v" || version != 1 {
+		t.Fatalf("GetVersioned old = %q, %d, %v", got, version, err)
+	}
+	if err := shared.SetWithTTL(ctx, "new", []byte("x"), time.Hour); err != nil {
+		t.Fatalf("SetWithTTL: %v", err)
+	}
+	shared.Close()
+	if err := legacy.Ping(); err != nil {
+		t.Fatalf("shared handle closed by store: %v", err)
+	}
+
+	kvOnly, err := OpenSQLiteStoreWithOptions(dir+"/kv.db", SQLiteStoreOptions{KVOnly: true})
+	if err != nil {
+		t.Fatalf("OpenSQLiteStoreWithOptions: %v", err)
+	}
+	defer kvOnly.Close()
+	if err := kvOnly.Set(ctx, "k", []byte("v")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	raw, err := db.OpenWithOptions(dir+"/kv.db", db.Options{SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("open kv file: %v", err)
+	}
+	defer raw.Close()
+	var tables string
+	raw.QueryRow(`SELECT group_concat(name, ',') FROM (SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name)`).Scan(&tables)
+	if tables != "kv_meta,kv_store,schema_migrations" {
+		t.Fatalf("standalone tables = %q", tables)
+	}
+}
+
+func TestVersionsSurviveDelete(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	sqliteKV, err := OpenSQLiteStore(dir + "/aba.db")
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore: %v", err)
+	}
+	defer sqliteKV.Close()
+	fileKV, err := OpenFileStore(dir + "/aba.json")
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	defer fileKV.Close()
+
+	for name, kv := range map[string]KVStore{"sqlite": sqliteKV, "memory": NewMemoryStore(), "file": fileKV} {
+		t.Run(name, func(t *testing.T) {
+			if err := kv.Set(ctx, "k", []byte("a")); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			_, stale, err := kv.GetVersioned(ctx, "k")
+			if err != nil {
+				t.Fatalf("GetVersioned: %v", err)
+			}
+			if err := kv.Delete(ctx, "k"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if err := kv.Set(ctx, "k", []byte("b")); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			if _, err := kv.CompareAndSet(ctx, "k", stale, []byte("c")); !errors.Is(err, ErrVersionMismatch) {
+				t.Fatalf("CompareAndSet with pre-delete version: expected ErrVersionMismatch, got %v", err)
+			}
+			if value, version, err := kv.GetVersioned(ctx, "k"); err != nil || string(value) != "b" || version <= stale {
+				t.Fatalf("GetVersioned = %q, %d, %v (stale %d)", value, version, err, stale)
+			}
+		})
+	}
+}
+
+func TestFileStoreDiscardsUnsavedWrites(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "kv")
+	kv, err := OpenFileStore(filepath.Join(dir, "kv.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	defer kv.Close()
+	if err := kv.Set(ctx, "saved", []byte("1")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// Without its directory the store can neither save nor find the file.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.Set(ctx, "unsaved", []byte("2")); err == nil {
+		t.Fatalf("Set succeeded without a directory to save into")
+	}
+	if _, err := kv.Get(ctx, "unsaved"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of unsaved key = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultTieredTTL bounds how long Tiered serves a cached value.
+const defaultTieredTTL = time.Minute
+
+// TieredOptions configures Tiered.
+type TieredOptions struct {
+	// TTL is how long a value read from the backing store stays cached
+	// (default one minute). It also bounds how long a cached value can
+	// outlive a shorter expiry set on the backing store.
+	TTL time.Duration
+}
+
+// tieredStore caches reads from back in front. Writes go to back and
+// invalidate front; changes seen by back.Watch, including writes by other
+// processes, invalidate it too.
+type tieredStore struct {
+	front, back KVStore
+	ttl         time.Duration
+	cancel      context.CancelFunc
+	// done is closed when the invalidation goroutine has stopped.
+	done chan struct{}
+}
+
+// Tiered layers a cache over a backing store for read-heavy state, e.g. an
+// LRU store over SQLite:
+//
+//	kv, err := store.Tiered(store.NewLRUStore(store.LRUOptions{MaxEntries: 1000}), sqliteKV, store.TieredOptions{})
+//
+// Only Get is served from front; versioned reads, listings and watches go to
+// back. It fails if back cannot be watched, since the cache would never be
+// invalidated. Closing the tiered store closes both.
+func Tiered(front, back KVStore, opts TieredOptions) (KVStore, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := back.Watch(ctx, "")
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("watch backing store: %w", err)
+	}
+	s := &tieredStore{front: front, back: back, ttl: opts.TTL, cancel: cancel, done: make(chan struct{})}
+	if s.ttl <= 0 {
+		s.ttl = defaultTieredTTL
+	}
+	go func() {
+		defer close(s.done)
+		for ev := range events {
+			front.Delete(ctx, ev.Key)
+		}
+	}()
+	return s, nil
+}
+
+func (s *tieredStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, err := s.front.Get(ctx, key); err == nil {
+		return value, nil
+	}
+	value, err := s.back.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	// A failed fill only costs a future cache miss.
+	_ = s.front.SetWithTTL(ctx, key, value, s.ttl)
+	return value, nil
+}
+
+func (s *tieredStore) GetVersioned(ctx context.Context, key string) ([]byte, int64, error) {
+	return s.back.GetVersioned(ctx, key)
+}
+
+func (s *tieredStore) Set(ctx context.Context, key string, value []byte) error {
+	return s.SetWithTTL(ctx, key, value, 0)
+}
+
+func (s *tieredStore) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.back.SetWithTTL(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	return s.front.Delete(ctx, key)
+}
+
+func (s *tieredStore) CompareAndSet(ctx context.Context, key string, expected int64, value []byte) (int64, error) {
+	version, err := s.back.CompareAndSet(ctx, key, expected, value)
+	if err != nil {
+		return 0, err
+	}
+	return version, s.front.Delete(ctx, key)
+}
+
+func (s *tieredStore) Delete(ctx context.Context, key string) error {
+	if err := s.back.Delete(ctx, key); err != nil {
+		return err
+	}
+	return s.front.Delete(ctx, key)
+}
+
+func (s *tieredStore) Purge(ctx context.Context) (int, error) {
+	n, err := s.back.Purge(ctx)
+	if err != nil {
+		return n, err
+	}
+	_, err = s.front.Purge(ctx)
+	return n, err
+}
+
+func (s *tieredStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return s.back.List(ctx, prefix)
+}
+
+func (s *tieredStore) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) bool) error {
+	return s.back.Scan(ctx, prefix, fn)
+}
+
+func (s *tieredStore) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	n, err := s.back.DeletePrefix(ctx, prefix)
+	if err != nil {
+		return n, err
+	}
+	_, err = s.front.DeletePrefix(ctx, prefix)
+	return n, err
+}
+
+func (s *tieredStore) Txn(ctx context.Context, fn func(tx KVTxn) error) error {
+	var written []string
+	err := s.back.Txn(ctx, func(tx KVTxn) error {
+		written = written[:0]
+		return fn(&recordingTxn{KVTxn: tx, written: &written})
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range written {
+		if err := s.front.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *tieredStore) Watch(ctx context.Context, prefix string) (<-chan KVEvent, error) {
+	return s.back.Watch(ctx, prefix)
+}
+
+func (s *tieredStore) Close() error {
+	s.cancel()
+	// Stop invalidating before front goes away.
+	<-s.done
+	ferr := s.front.Close()
+	if err := s.back.Close(); err != nil {
+		return err
+	}
+	return ferr
+}
+
+// recordingTxn notes the keys a transaction writes.
+type recordingTxn struct {
+	KVTxn
+	written *[]string
+}
+
+func (t *recordingTxn) Set(ctx context.Context, key string, value []byte) error {
+	*t.written = append(*t.written, key)
+	return t.KVTxn.Set(ctx, key, value)
+}
+
+func (t *recordingTxn) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	*t.written = append(*t.written, key)
+	return t.KVTxn.SetWithTTL(ctx, key, value, ttl)
+}
+
+func (t *recordingTxn) Delete(ctx context.Context, key string) error {
+	*t.written = append(*t.written, key)
+	return t.KVTxn.Delete(ctx, key)
+}
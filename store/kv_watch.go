@@ -0,0 +1,254 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/arc-sdk/db"
+)
+
+// defaultWatchInterval is how often sqliteStore checks for changes made by
+// other connections and processes.
+const defaultWatchInterval = 250 * time.Millisecond
+
+// KVEvent reports that a key changed. Events for the same key are coalesced
+// while the receiver is busy, so Value is always the latest one.
+type KVEvent struct {
+	Key string
+	// Value is the new value; nil when Deleted.
+	Value   []byte
+	Deleted bool
+}
+
+// watcher queues events for one Watch call and delivers them in order.
+type watcher struct {
+	prefix  string
+	mu      sync.Mutex
+	pending map[string]KVEvent
+	order   []string
+	notify  chan struct{}
+	out     chan KVEvent
+}
+
+func newWatcher(prefix string) *watcher {
+	return &watcher{
+		prefix:  prefix,
+		pending: make(map[string]KVEvent),
+		notify:  make(chan struct{}, 1),
+		out:     make(chan KVEvent),
+	}
+}
+
+// push queues ev without blocking, replacing any undelivered event for the
+// same key.
+func (w *watcher) push(ev KVEvent) {
+	if !strings.HasPrefix(ev.Key, w.prefix) {
+		return
+	}
+	w.mu.Lock()
+	if _, ok := w.pending[ev.Key]; !ok {
+		w.order = append(w.order, ev.Key)
+	}
+	w.pending[ev.Key] = ev
+	w.mu.Unlock()
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers queued events until ctx is cancelled or done is closed, then
+// closes the output channel.
+func (w *watcher) run(ctx context.Context, done <-chan struct{}) {
+	defer close(w.out)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-w.notify:
+		}
+
+		w.mu.Lock()
+		events := make([]KVEvent, len(w.order))
+		for i, k := range w.order {
+			events[i] = w.pending[k]
+		}
+		w.order = w.order[:0]
+		clear(w.pending)
+		w.mu.Unlock()
+
+		for _, ev := range events {
+			select {
+			case w.out <- ev:
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+// watchHub fans in-process changes out to watchers.
+type watchHub struct {
+	mu       sync.Mutex
+	watchers map[*watcher]struct{}
+}
+
+func (h *watchHub) watch(ctx context.Context, prefix string, done <-chan struct{}) <-chan KVEvent {
+	w := newWatcher(prefix)
+	h.mu.Lock()
+	if h.watchers == nil {
+		h.watchers = make(map[*watcher]struct{})
+	}
+	h.watchers[w] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		w.run(ctx, done)
+		h.mu.Lock()
+		delete(h.watchers, w)
+		h.mu.Unlock()
+	}()
+	return w.out
+}
+
+func (h *watchHub) publish(ev KVEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.watchers {
+		w.push(ev)
+	}
+}
+
+func (s *memoryStore) Watch(ctx context.Context, prefix string) (<-chan KVEvent, error) {
+	return s.hub.watch(ctx, prefix, s.done), nil
+}
+
+// kvStamp identifies one write to a key.
+type kvStamp struct {
+	version   int64
+	updatedAt int64
+}
+
+// Watch polls a fingerprint of kv_store through the pool: the store-wide
+// version sequence, which every write advances, and the row count, which
+// every delete lowers. When it changes, Watch diffs the watched keys. No
+// connection is held between polls, so it works on single-connection
+// handles such as an OpenPool writer, and it sees writes from every
+// connection and process, including through a shared-cache :memory: handle.
+func (s *sqliteStore) Watch(ctx context.Context, prefix string) (<-chan KVEvent, error) {
+	mark, err := kvFingerprint(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	seen, err := kvSnapshot(ctx, s.db, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	w := newWatcher(prefix)
+	// poll diffs the watched keys if the fingerprint moved. Events are sent
+	// only once every read has succeeded, so a failed poll can be retried
+	// without repeating any.
+	poll := func() error {
+		m, err := kvFingerprint(ctx, s.db)
+		if err != nil || m == mark {
+			return err
+		}
+		current, err := kvSnapshot(ctx, s.db, prefix)
+		if err != nil {
+			return err
+		}
+		var events []KVEvent
+		for key, stamp := range current {
+			if old, ok := seen[key]; ok && old == stamp {
+				continue
+			}
+			var value sql.NullString
+			err := s.db.QueryRowContext(ctx, `SELECT value FROM kv_store WHERE key = ?`, key).Scan(&value)
+			if err == sql.ErrNoRows {
+				// Deleted since the snapshot: treat it as absent.
+				delete(current, key)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			events = append(events, KVEvent{Key: key, Value: []byte(value.String)})
+		}
+		for key := range seen {
+			if _, ok := current[key]; !ok {
+				events = append(events, KVEvent{Key: key, Deleted: true})
+			}
+		}
+		mark, seen = m, current
+		for _, ev := range events {
+			w.push(ev)
+		}
+		return nil
+	}
+
+	// The poller owns stop, so delivery ends with it: on cancellation, Close
+	// or a polling error. A busy or locked database is retried next tick.
+	stop := make(chan struct{})
+	go w.run(ctx, stop)
+	go func() {
+		defer close(stop)
+		ticker := time.NewTicker(s.watchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			case <-ticker.C:
+			}
+			if err := poll(); err != nil && !db.IsBusy(err) {
+				return
+			}
+		}
+	}()
+	return w.out, nil
+}
+
+// kvFingerprint changes whenever kv_store does (see Watch).
+func kvFingerprint(ctx context.Context, q db.Querier) ([2]int64, error) {
+	var m [2]int64
+	err := q.QueryRowContext(ctx, `
+		SELECT (SELECT value FROM kv_meta WHERE name = 'version_seq'), (SELECT COUNT(*) FROM kv_store)
+	`).Scan(&m[0], &m[1])
+	return m, err
+}
+
+// kvSnapshot returns the write stamp of every live key with prefix.
+func kvSnapshot(ctx context.Context, q db.Querier, prefix string) (map[string]kvStamp, error) {
+	where, args := prefixRange(prefix)
+	rows, err := q.QueryContext(ctx, `
+		SELECT key, version, updated_at FROM kv_store
+		WHERE `+where+` AND (expires_at IS NULL OR expires_at > ?)
+	`, append(args, time.Now().UnixNano())...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]kvStamp)
+	for rows.Next() {
+		var key string
+		var stamp kvStamp
+		if err := rows.Scan(&key, &stamp.version, &stamp.updatedAt); err != nil {
+			return nil, err
+		}
+		out[key] = stamp
+	}
+	return out, rows.Err()
+}
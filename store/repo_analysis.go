@@ -0,0 +1,203 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/yourorg/arc-sdk/db"
+)
+
+// AnalysisHistoryOptions filters RepoAnalysisStore.History.
+type AnalysisHistoryOptions struct {
+	// Type limits results to one analysis_type.
+	Type string
+	// Since excludes analyses before this Unix time.
+	Since       int64
+	SuccessOnly bool
+	Limit       int
+}
+
+// AnalysisSearchOptions filters RepoAnalysisStore.Search.
+type AnalysisSearchOptions struct {
+	RepoName string
+	Type     string
+	Limit    int
+}
+
+// RepoAnalysisStore records repository analyses in the repo_analysis table.
+// Per-repo summaries are exposed on Repo by ReposStore.
+type RepoAnalysisStore struct {
+	DB db.Querier
+}
+
+// NewRepoAnalysisStore creates a new RepoAnalysisStore.
+func NewRepoAnalysisStore(q db.Querier) *RepoAnalysisStore {
+	return &RepoAnalysisStore{DB: q}
+}
+
+// analysisColumns lists the RepoAnalysis columns of repo_analysis (aliased ra)
+// in the order scanAnalysis expects.
+const analysisColumns = `ra.id, ra.repo_name, ra.analysis_type, ra.prompt_template, ra.full_prompt,
+		       ra.analyzed_at, ra.analyzed_by, ra.model, ra.feature_name, ra.context_files,
+		       ra.tokens_used, ra.success, ra.output_path, ra.notes`
+
+// Record inserts an analysis and returns its ID. AnalyzedAt defaults to now;
+// Success is stored as given, so set it for completed runs. The repo must
+// exist in external_repos.
+func (s *RepoAnalysisStore) Record(ctx context.Context, a RepoAnalysis) (int64, error) {
+	if a.AnalyzedAt == 0 {
+		a.AnalyzedAt = time.Now().Unix()
+	}
+	contextJSON, err := json.Marshal(a.ContextFiles)
+	if err != nil {
+		return 0, fmt.Errorf("marshal context files: %w", err)
+	}
+
+	var id int64
+	err = s.DB.QueryRowContext(ctx, `
+		INSERT INTO repo_analysis(
+			repo_name, analysis_type, prompt_template, full_prompt,
+			analyzed_at, analyzed_by, model, feature_name, context_files,
+			tokens_used, success, output_path, notes
+		) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?)
+		RETURNING id
+	`,
+		a.RepoName, a.AnalysisType, a.PromptTemplate, a.FullPrompt,
+		a.AnalyzedAt, a.AnalyzedBy, a.Model, a.FeatureName, string(contextJSON),
+		a.TokensUsed, boolToInt(a.Success), a.OutputPath, a.Notes,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("record analysis for %s: %w", a.RepoName, err)
+	}
+	return id, nil
+}
+
+// Get retrieves an analysis by ID.
+func (s *RepoAnalysisStore) Get(ctx context.Context, id int64) (*RepoAnalysis, error) {
+	row := s.DB.QueryRowContext(ctx, `SELECT `+analysisColumns+` FROM repo_analysis ra WHERE ra.id = ?`, id)
+	return scanAnalysis(row)
+}
+
+// Latest returns the most recent analysis of a repo, optionally of one type.
+// It returns sql.ErrNoRows if there is none.
+func (s *RepoAnalysisStore) Latest(ctx context.Context, repoName, analysisType string) (*RepoAnalysis, error) {
+	list, err := s.History(ctx, repoName, AnalysisHistoryOptions{Type: analysisType, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &list[0], nil
+}
+
+// History returns a repo's analyses, newest first.
+func (s *RepoAnalysisStore) History(ctx context.Context, repoName string, opts AnalysisHistoryOptions) ([]RepoAnalysis, error) {
+	query := `SELECT ` + analysisColumns + ` FROM repo_analysis ra WHERE ra.repo_name = ?`
+	args := []any{repoName}
+	if opts.Type != "" {
+		query += " AND ra.analysis_type = ?"
+		args = append(args, opts.Type)
+	}
+	if opts.Since > 0 {
+		query += " AND ra.analyzed_at >= ?"
+		args = append(args, opts.Since)
+	}
+	if opts.SuccessOnly {
+		query += " AND ra.success = 1"
+	}
+	query += " ORDER BY ra.analyzed_at DESC, ra.id DESC"
+	if opts.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanAnalyses(rows)
+}
+
+// Search runs an FTS5 query over analysis types, prompts, feature names and
+// notes, best matches first.
+func (s *RepoAnalysisStore) Search(ctx context.Context, query string, opts AnalysisSearchOptions) ([]RepoAnalysis, error) {
+	sqlQuery := `
+		SELECT ` + analysisColumns + `
+		FROM repo_analysis ra
+		JOIN repo_analysis_fts fts ON fts.rowid = ra.id
+		WHERE repo_analysis_fts MATCH ?`
+	args := []any{query}
+	if opts.RepoName != "" {
+		sqlQuery += " AND ra.repo_name = ?"
+		args = append(args, opts.RepoName)
+	}
+	if opts.Type != "" {
+		sqlQuery += " AND ra.analysis_type = ?"
+		args = append(args, opts.Type)
+	}
+	sqlQuery += " ORDER BY bm25(repo_analysis_fts), ra.analyzed_at DESC"
+	if opts.Limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanAnalyses(rows)
+}
+
+// Delete removes an analysis by ID.
+func (s *RepoAnalysisStore) Delete(ctx context.Context, id int64) error {
+	_, err := s.DB.ExecContext(ctx, `DELETE FROM repo_analysis WHERE id = ?`, id)
+	return err
+}
+
+func scanAnalyses(rows *sql.Rows) ([]RepoAnalysis, error) {
+	var out []RepoAnalysis
+	for rows.Next() {
+		a, err := scanAnalysis(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *a)
+	}
+	return out, rows.Err()
+}
+
+func scanAnalysis(row interface{ Scan(dest ...any) error }) (*RepoAnalysis, error) {
+	var a RepoAnalysis
+	var fullPrompt, model, featureName, contextJSON, outputPath, notes sql.NullString
+	var tokens sql.NullInt64
+	var success sql.NullInt64
+	err := row.Scan(
+		&a.ID, &a.RepoName, &a.AnalysisType, &a.PromptTemplate, &fullPrompt,
+		&a.AnalyzedAt, &a.AnalyzedBy, &model, &featureName, &contextJSON,
+		&tokens, &success, &outputPath, &notes,
+	)
+	if err != nil {
+		return nil, err
+	}
+	a.FullPrompt = fullPrompt.String
+	a.Model = model.String
+	a.FeatureName = featureName.String
+	a.TokensUsed = int(tokens.Int64)
+	// success defaults to 1 in the schema.
+	a.Success = !success.Valid || success.Int64 != 0
+	a.OutputPath = outputPath.String
+	a.Notes = notes.String
+	if contextJSON.Valid && contextJSON.String != "" && contextJSON.String != "null" {
+		_ = json.Unmarshal([]byte(contextJSON.String), &a.ContextFiles)
+	}
+	return &a, nil
+}
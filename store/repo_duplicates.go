@@ -0,0 +1,230 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourorg/arc-sdk/db"
+	"github.com/yourorg/arc-sdk/git"
+)
+
+// DuplicateGroup is a set of repos that are the same project.
+type DuplicateGroup struct {
+	CanonicalURL string
+	// Repos are ordered oldest first, so Repos[0] is the natural one to keep.
+	Repos []Repo
+}
+
+// FindDuplicates returns the groups of repos whose URLs have the same
+// canonical form, or whose URL is a location another repo moved from.
+func (s *ReposStore) FindDuplicates(ctx context.Context) ([]DuplicateGroup, error) {
+	repos, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	redirects, err := s.redirects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	canonical := make(map[string]string, len(repos))
+	for _, r := range repos {
+		canonical[r.Name] = git.CanonicalURL(r.URL)
+	}
+	groups := make(map[string][]Repo)
+	for _, r := range repos {
+		key := canonical[r.Name]
+		if target, ok := redirects[key]; ok && target != r.Name {
+			key = canonical[target]
+		}
+		groups[key] = append(groups[key], r)
+	}
+
+	var out []DuplicateGroup
+	for key, list := range groups {
+		if len(list) < 2 {
+			continue
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].AddedAt != list[j].AddedAt {
+				return list[i].AddedAt < list[j].AddedAt
+			}
+			return list[i].Name < list[j].Name
+		})
+		out = append(out, DuplicateGroup{CanonicalURL: key, Repos: list})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CanonicalURL < out[j].CanonicalURL })
+	return out, nil
+}
+
+// redirects maps old canonical URLs to the repos that moved from them.
+func (s *ReposStore) redirects(ctx context.Context) (map[string]string, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT canonical_url, repo_name FROM repo_redirects`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]string)
+	for rows.Next() {
+		var url, name string
+		if err := rows.Scan(&url, &name); err != nil {
+			return nil, err
+		}
+		out[url] = name
+	}
+	return out, rows.Err()
+}
+
+// Merge folds the repo dup into keep and deletes dup. Tags are combined,
+// notes joined, access counts summed and empty fields of keep filled from
+// dup; analyses, dependencies, redirects and enrichment state move to keep
+// (keep's own enrichment state wins), and dup's URL becomes a redirect to
+// keep.
+func (s *ReposStore) Merge(ctx context.Context, keep, dup string) error {
+	if keep == dup {
+		return fmt.Errorf("merge %s into itself", keep)
+	}
+	return db.InTx(ctx, s.DB, func(tx *sql.Tx) error {
+		repos := NewReposStore(tx)
+		k, err := repos.Get(ctx, keep)
+		if err != nil {
+			return fmt.Errorf("get %s: %w", keep, err)
+		}
+		d, err := repos.Get(ctx, dup)
+		if err != nil {
+			return fmt.Errorf("get %s: %w", dup, err)
+		}
+		mergeRepo(k, d)
+
+		for _, q := range []string{
+			`UPDATE repo_analysis SET repo_name = ? WHERE repo_name = ?`,
+			// keep's own entry for a dependency wins; dup's goes with it.
+			`UPDATE OR IGNORE repo_dependencies SET repo_name = ? WHERE repo_name = ?`,
+			`UPDATE repo_redirects SET repo_name = ? WHERE repo_name = ?`,
+			`UPDATE OR IGNORE repo_enrichment SET repo_name = ? WHERE repo_name = ?`,
+		} {
+			if _, err := tx.ExecContext(ctx, q, keep, dup); err != nil {
+				return fmt.Errorf("merge %s into %s: %w", dup, keep, err)
+			}
+		}
+		if err := repos.Delete(ctx, dup); err != nil {
+			return err
+		}
+		if err := repos.Upsert(ctx, *k); err != nil {
+			return err
+		}
+		// Upsert never changes added_at.
+		if _, err := tx.ExecContext(ctx, `UPDATE external_repos SET added_at = ? WHERE name = ?`, k.AddedAt, keep); err != nil {
+			return err
+		}
+		return repos.addRedirect(ctx, git.CanonicalURL(d.URL), k)
+	})
+}
+
+// mergeRepo combines dup's fields into keep.
+func mergeRepo(keep, dup *Repo) {
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fill(&keep.Description, dup.Description)
+	fill(&keep.Language, dup.Language)
+	fill(&keep.License, dup.License)
+	fill(&keep.Homepage, dup.Homepage)
+	fill(&keep.DefaultBranch, dup.DefaultBranch)
+	if len(keep.Topics) == 0 {
+		keep.Topics = dup.Topics
+	}
+	keep.Tags = normalizeTags(append(keep.Tags, dup.Tags...))
+	if dup.Notes != "" && !strings.Contains(keep.Notes, dup.Notes) {
+		if keep.Notes != "" {
+			keep.Notes += "\n\n"
+		}
+		keep.Notes += dup.Notes
+	}
+
+	keep.AccessCount += dup.AccessCount
+	keep.LastOpenedAt = max(keep.LastOpenedAt, dup.LastOpenedAt)
+	keep.AddedAt = min(keep.AddedAt, dup.AddedAt)
+	keep.Stars = max(keep.Stars, dup.Stars)
+	keep.Forks = max(keep.Forks, dup.Forks)
+	keep.CommitCount = max(keep.CommitCount, dup.CommitCount)
+	keep.LastCommitAt = max(keep.LastCommitAt, dup.LastCommitAt)
+	if !keep.Cloned && dup.Cloned {
+		keep.Cloned = true
+		keep.ClonePath = dup.ClonePath
+		keep.ClonedAt = dup.ClonedAt
+		keep.UpdatedAt = dup.UpdatedAt
+		keep.Shallow = dup.Shallow
+	}
+}
+
+// Move records that a repo now lives at newURL, as when its forge reports a
+// rename. The old URL becomes a redirect, so GetByURL still finds the repo
+// by it, and a repo already stored under newURL is merged into this one.
+func (s *ReposStore) Move(ctx context.Context, name, newURL string) error {
+	return db.InTx(ctx, s.DB, func(tx *sql.Tx) error {
+		repos := NewReposStore(tx)
+		repo, err := repos.Get(ctx, name)
+		if err != nil {
+			return fmt.Errorf("get %s: %w", name, err)
+		}
+		if repo.URL == newURL {
+			return nil
+		}
+		oldURL := git.CanonicalURL(repo.URL)
+
+		other, err := repos.GetByURL(ctx, newURL)
+		switch {
+		case err == nil && other.Name != name:
+			if err := repos.Merge(ctx, name, other.Name); err != nil {
+				return err
+			}
+		case err != nil && !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+
+		owner, repoName := repo.Owner, repo.Repo
+		if parsed, err := git.ParseGitURL(newURL); err == nil {
+			owner, repoName = parsed.Owner, parsed.Name
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE external_repos SET url = ?, canonical_url = ?, owner = ?, repo = ?
+			WHERE name = ?
+		`, newURL, git.CanonicalURL(newURL), owner, repoName, name)
+		if err != nil {
+			return fmt.Errorf("move %s: %w", name, err)
+		}
+		// A redirect from the new location is stale, e.g. after moving back.
+		if _, err := tx.ExecContext(ctx, `DELETE FROM repo_redirects WHERE canonical_url = ?`, git.CanonicalURL(newURL)); err != nil {
+			return err
+		}
+		repo.URL = newURL
+		return repos.addRedirect(ctx, oldURL, repo)
+	})
+}
+
+// addRedirect points the canonical URL from at repo, unless it is repo's own.
+func (s *ReposStore) addRedirect(ctx context.Context, from string, repo *Repo) error {
+	if from == git.CanonicalURL(repo.URL) {
+		return nil
+	}
+	_, err := s.DB.ExecContext(ctx, `
+		INSERT INTO repo_redirects(canonical_url, repo_name, created_at) VALUES(?, ?, ?)
+		ON CONFLICT(canonical_url) DO UPDATE SET repo_name = excluded.repo_name, created_at = excluded.created_at
+	`, from, repo.Name, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("add redirect %s: %w", from, err)
+	}
+	return nil
+}
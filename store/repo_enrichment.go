@@ -0,0 +1,131 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// RepoMetadata is the forge-reported subset of Repo written by
+// ReposStore.UpdateMetadata.
+type RepoMetadata struct {
+	Description   string
+	Language      string
+	Topics        []string
+	Stars         int
+	Forks         int
+	License       string
+	Homepage      string
+	DefaultBranch string
+	Archived      bool
+}
+
+// RepoEnrichment is the enrichment state of one repo.
+type RepoEnrichment struct {
+	RepoName string
+	// Provider is the forge host the metadata came from.
+	Provider string
+	ETag     string
+	// EnrichedAt is the last successful fetch; 0 if none succeeded yet.
+	EnrichedAt int64
+	CheckedAt  int64
+	// Error is the last attempt's error, empty if it succeeded.
+	Error string
+}
+
+// UpdateMetadata writes forge metadata onto a repo. Empty strings and nil
+// Topics leave the stored values alone, since not every forge reports every
+// field; counts and Archived are always written. It returns sql.ErrNoRows if
+// the repo does not exist.
+func (s *ReposStore) UpdateMetadata(ctx context.Context, name string, m RepoMetadata) error {
+	var topicsJSON any
+	if m.Topics != nil {
+		raw, err := json.Marshal(m.Topics)
+		if err != nil {
+			return fmt.Errorf("marshal topics: %w", err)
+		}
+		topicsJSON = string(raw)
+	}
+
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE external_repos SET
+			description = COALESCE(NULLIF(?, ''), description),
+			language = COALESCE(NULLIF(?, ''), language),
+			topics = COALESCE(?, topics),
+			stars = ?,
+			forks = ?,
+			license = COALESCE(NULLIF(?, ''), license),
+			homepage = COALESCE(NULLIF(?, ''), homepage),
+			default_branch = COALESCE(NULLIF(?, ''), default_branch),
+			archived = ?
+		WHERE name = ?
+	`,
+		m.Description, m.Language, topicsJSON, m.Stars, m.Forks,
+		m.License, m.Homepage, m.DefaultBranch, boolToInt(m.Archived),
+		name,
+	)
+	if err != nil {
+		return fmt.Errorf("update metadata for %s: %w", name, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Enrichment returns a repo's enrichment state, or sql.ErrNoRows if it was
+// never enriched.
+func (s *ReposStore) Enrichment(ctx context.Context, name string) (*RepoEnrichment, error) {
+	var e RepoEnrichment
+	var etag, errText sql.NullString
+	var enrichedAt sql.NullInt64
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT repo_name, provider, etag, enriched_at, checked_at, error
+		FROM repo_enrichment WHERE repo_name = ?
+	`, name).Scan(&e.RepoName, &e.Provider, &etag, &enrichedAt, &e.CheckedAt, &errText)
+	if err != nil {
+		return nil, err
+	}
+	e.ETag = etag.String
+	e.EnrichedAt = enrichedAt.Int64
+	e.Error = errText.String
+	return &e, nil
+}
+
+// RecordEnrichment stores the outcome of an enrichment attempt. A failed
+// attempt (non-empty Error) keeps the previous ETag and EnrichedAt.
+func (s *ReposStore) RecordEnrichment(ctx context.Context, e RepoEnrichment) error {
+	_, err := s.DB.ExecContext(ctx, `
+		INSERT INTO repo_enrichment(repo_name, provider, etag, enriched_at, checked_at, error)
+		VALUES(?, ?, NULLIF(?, ''), NULLIF(?, 0), ?, NULLIF(?, ''))
+		ON CONFLICT(repo_name) DO UPDATE SET
+			provider = excluded.provider,
+			etag = CASE WHEN excluded.error IS NULL THEN excluded.etag ELSE etag END,
+			enriched_at = CASE WHEN excluded.error IS NULL THEN excluded.enriched_at ELSE enriched_at END,
+			checked_at = excluded.checked_at,
+			error = excluded.error
+	`, e.RepoName, e.Provider, e.ETag, e.EnrichedAt, e.CheckedAt, e.Error)
+	if err != nil {
+		return fmt.Errorf("record enrichment for %s: %w", e.RepoName, err)
+	}
+	return nil
+}
+
+// NotEnrichedSince returns repos not successfully enriched at or after the
+// Unix time cutoff, including repos never enriched, stalest first.
+func (s *ReposStore) NotEnrichedSince(ctx context.Context, cutoff int64) ([]Repo, error) {
+	rows, err := s.DB.QueryContext(ctx, repoSelect+`
+		WHERE COALESCE(re.enriched_at, 0) < ?
+		ORDER BY re.enriched_at NULLS FIRST, r.name
+	`, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return s.scanRepos(rows)
+}
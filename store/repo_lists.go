@@ -0,0 +1,588 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/yourorg/arc-sdk/db"
+	"github.com/yourorg/arc-sdk/git"
+	"gopkg.in/yaml.v3"
+)
+
+// RepoListFormat names a repo list format for ReposStore.Import and Export.
+type RepoListFormat string
+
+const (
+	// FormatYAML and FormatJSON are a Manifest.
+	FormatYAML RepoListFormat = "yaml"
+	FormatJSON RepoListFormat = "json"
+	// FormatMarkdown is an "awesome list": repo links in bullet lists under
+	// section headings.
+	FormatMarkdown RepoListFormat = "markdown"
+	// FormatGitHubStars is the JSON array returned by GitHub's
+	// /users/{user}/starred endpoint, with or without starred_at wrappers.
+	FormatGitHubStars RepoListFormat = "github-stars"
+)
+
+// Manifest is a hand-editable list of repos. Groups nest like OPML
+// outlines; a repo in a group is tagged with the group path, e.g. "ml/nlp".
+type Manifest struct {
+	Version int             `yaml:"version" json:"version"`
+	Repos   []ManifestRepo  `yaml:"repos,omitempty" json:"repos,omitempty"`
+	Groups  []ManifestGroup `yaml:"groups,omitempty" json:"groups,omitempty"`
+}
+
+// ManifestGroup is a named group of manifest repos.
+type ManifestGroup struct {
+	Name   string          `yaml:"name" json:"name"`
+	Repos  []ManifestRepo  `yaml:"repos,omitempty" json:"repos,omitempty"`
+	Groups []ManifestGroup `yaml:"groups,omitempty" json:"groups,omitempty"`
+}
+
+// ManifestRepo is one repo in a manifest; only URL is required.
+type ManifestRepo struct {
+	URL         string   `yaml:"url" json:"url"`
+	Name        string   `yaml:"name,omitempty" json:"name,omitempty"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Language    string   `yaml:"language,omitempty" json:"language,omitempty"`
+	Topics      []string `yaml:"topics,omitempty" json:"topics,omitempty"`
+	Tags        []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Notes       string   `yaml:"notes,omitempty" json:"notes,omitempty"`
+}
+
+// ImportOptions configures ReposStore.Import.
+type ImportOptions struct {
+	Format RepoListFormat
+	// AddedBy is recorded on new repos; it defaults to "import".
+	AddedBy string
+	// Tags are added to every imported repo.
+	Tags []string
+	// DryRun reports what would change without writing.
+	DryRun bool
+}
+
+// ImportResult reports what ReposStore.Import did, by repo name.
+type ImportResult struct {
+	Added []string
+	// Updated repos already existed and gained tags or empty fields.
+	Updated []string
+	// Unchanged repos already existed with everything imported.
+	Unchanged []string
+	// Invalid lists entries that could not be imported.
+	Invalid []ImportError
+}
+
+// ImportError is an entry Import skipped.
+type ImportError struct {
+	URL string
+	Err error
+}
+
+func (e ImportError) Error() string {
+	return fmt.Sprintf("%s: %v", e.URL, e.Err)
+}
+
+// Import reads a repo list and upserts its repos. Each URL is parsed with
+// git.ParseGitURL; a repo GetByURL already finds for it is merged rather than
+// duplicated: it gains the imported tags and fills its empty description,
+// language and topics. The import is atomic; entries that cannot be parsed
+// are reported in Invalid.
+func (s *ReposStore) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	var entries []Repo
+	var err error
+	switch opts.Format {
+	case FormatYAML, FormatJSON:
+		entries, err = readManifest(r, opts.Format)
+	case FormatMarkdown:
+		entries, err = readAwesomeList(r)
+	case FormatGitHubStars:
+		entries, err = readGitHubStars(r)
+	default:
+		return nil, fmt.Errorf("unknown repo list format %q", opts.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s repo list: %w", opts.Format, err)
+	}
+	if opts.AddedBy == "" {
+		opts.AddedBy = "import"
+	}
+
+	res := &ImportResult{}
+	err = db.InTx(ctx, s.DB, func(tx *sql.Tx) error {
+		repos := NewReposStore(tx)
+		now := time.Now().Unix()
+		// Repos handled so far, by canonical URL, and what happened to them.
+		seen := make(map[string]string)
+		var order []string
+		status := make(map[string]*[]string)
+
+		for _, e := range entries {
+			parsed, err := git.ParseGitURL(e.URL)
+			if err != nil {
+				res.Invalid = append(res.Invalid, ImportError{URL: e.URL, Err: err})
+				continue
+			}
+			e.Tags = append(e.Tags, opts.Tags...)
+
+			existing, err := repos.findImported(ctx, parsed, seen)
+			if err != nil {
+				return err
+			}
+			if existing == nil {
+				repo, err := repos.newImported(ctx, e, parsed, opts.AddedBy, now)
+				if err != nil {
+					res.Invalid = append(res.Invalid, ImportError{URL: e.URL, Err: err})
+					continue
+				}
+				if err := repos.Upsert(ctx, *repo); err != nil {
+					return err
+				}
+				seen[parsed.WebURL()] = repo.Name
+				order = append(order, repo.Name)
+				status[repo.Name] = &res.Added
+				continue
+			}
+
+			seen[parsed.WebURL()] = existing.Name
+			changed := mergeImported(existing, e)
+			if changed {
+				if err := repos.Upsert(ctx, *existing); err != nil {
+					return err
+				}
+			}
+			// A repo listed twice keeps its first status, except that a
+			// later merge makes an unchanged repo updated.
+			switch prev, ok := status[existing.Name]; {
+			case !ok:
+				order = append(order, existing.Name)
+				status[existing.Name] = &res.Unchanged
+				if changed {
+					status[existing.Name] = &res.Updated
+				}
+			case changed && prev == &res.Unchanged:
+				status[existing.Name] = &res.Updated
+			}
+		}
+		for _, name := range order {
+			*status[name] = append(*status[name], name)
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return res, nil
+}
+
+// errDryRun rolls back a dry-run import.
+var errDryRun = errors.New("dry run")
+
+// findImported returns the stored repo for an imported URL, or nil if there
+// is none.
+func (s *ReposStore) findImported(ctx context.Context, parsed *git.ParsedURL, seen map[string]string) (*Repo, error) {
+	if name, ok := seen[parsed.WebURL()]; ok {
+		return s.Get(ctx, name)
+	}
+	repo, err := s.GetByURL(ctx, parsed.URL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return repo, err
+}
+
+// newImported builds a new repo from an import entry, choosing a name that is
+// not taken: the entry's name, the repo name, then owner-repo.
+func (s *ReposStore) newImported(ctx context.Context, e Repo, parsed *git.ParsedURL, addedBy string, now int64) (*Repo, error) {
+	candidates := []string{e.Name, parsed.Name, parsed.Owner + "-" + parsed.Name}
+	e.Name = ""
+	for _, name := range candidates {
+		if name == "" {
+			continue
+		}
+		_, err := s.Get(ctx, name)
+		if errors.Is(err, sql.ErrNoRows) {
+			e.Name = name
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if e.Name == "" {
+		return nil, fmt.Errorf("name %q is taken", parsed.Name)
+	}
+	e.Platform = parsed.Platform
+	e.Owner = parsed.Owner
+	e.Repo = parsed.Name
+	e.AddedAt = now
+	e.AddedBy = addedBy
+	e.Tags = normalizeTags(e.Tags)
+	return &e, nil
+}
+
+// mergeImported adds an import entry's tags to repo and fills its empty
+// fields, reporting whether anything changed.
+func mergeImported(repo *Repo, e Repo) bool {
+	changed := false
+	fill := func(dst *string, src string) {
+		if *dst == "" && src != "" {
+			*dst = src
+			changed = true
+		}
+	}
+	fill(&repo.Description, e.Description)
+	fill(&repo.Language, e.Language)
+	fill(&repo.Homepage, e.Homepage)
+	fill(&repo.License, e.License)
+	if len(repo.Topics) == 0 && len(e.Topics) > 0 {
+		repo.Topics = e.Topics
+		changed = true
+	}
+	tags := normalizeTags(append(append([]string{}, repo.Tags...), e.Tags...))
+	if len(tags) != len(normalizeTags(repo.Tags)) {
+		repo.Tags = tags
+		changed = true
+	}
+	return changed
+}
+
+func readManifest(r io.Reader, format RepoListFormat) ([]Repo, error) {
+	var m Manifest
+	if format == FormatJSON {
+		if err := json.NewDecoder(r).Decode(&m); err != nil {
+			return nil, err
+		}
+	} else if err := yaml.NewDecoder(r).Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	var out []Repo
+	var walk func(repos []ManifestRepo, groups []ManifestGroup, path string)
+	walk = func(repos []ManifestRepo, groups []ManifestGroup, path string) {
+		for _, mr := range repos {
+			repo := Repo{
+				Name:        mr.Name,
+				URL:         strings.TrimSpace(mr.URL),
+				Description: mr.Description,
+				Language:    mr.Language,
+				Topics:      mr.Topics,
+				Tags:        mr.Tags,
+				Notes:       mr.Notes,
+			}
+			if path != "" {
+				repo.Tags = append([]string{path}, repo.Tags...)
+			}
+			out = append(out, repo)
+		}
+		for _, g := range groups {
+			sub := NormalizeTag(g.Name)
+			if path != "" {
+				sub = path + "/" + sub
+			}
+			walk(g.Repos, g.Groups, sub)
+		}
+	}
+	walk(m.Repos, m.Groups, "")
+	return out, nil
+}
+
+var (
+	mdHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdItem    = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	mdLink    = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	mdInline  = regexp.MustCompile("(\\*\\*|__|`)")
+	// mdName is link text that can be a repo name, as Export writes it.
+	mdName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]*$`)
+)
+
+// readAwesomeList extracts repos from the bullet items of a Markdown list.
+// An item's first link to a known forge is the repo, its text the name if it
+// is one word, and the text after it the description; the headings above
+// it, below the title, form its tag.
+func readAwesomeList(r io.Reader) ([]Repo, error) {
+	var out []Repo
+	var sections []string
+	inFence := false
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			level := len(m[1])
+			if level == 1 {
+				sections = nil
+				continue
+			}
+			// Level 2 headings are top-level sections.
+			if level-2 < len(sections) {
+				sections = sections[:level-2]
+			}
+			for len(sections) < level-2 {
+				sections = append(sections, "")
+			}
+			sections = append(sections, sectionTag(m[2]))
+			continue
+		}
+		m := mdItem.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		item := m[1]
+		for _, link := range mdLink.FindAllStringSubmatchIndex(item, -1) {
+			url := item[link[4]:link[5]]
+			parsed, err := git.ParseGitURL(url)
+			if err != nil || parsed.Platform == "git" {
+				continue
+			}
+			repo := Repo{
+				URL:         parsed.WebURL(),
+				Description: linkDescription(item[link[1]:]),
+			}
+			if text := item[link[2]:link[3]]; mdName.MatchString(text) {
+				repo.Name = text
+			}
+			if tag := NormalizeTag(strings.Join(sections, "/")); tag != "" {
+				repo.Tags = []string{tag}
+			}
+			out = append(out, repo)
+			break
+		}
+	}
+	return out, sc.Err()
+}
+
+// sectionTag turns a heading into a tag segment: "Key-Value Stores" becomes
+// "key-value-stores".
+func sectionTag(heading string) string {
+	heading = mdLink.ReplaceAllString(heading, "$1")
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// linkDescription cleans the text following an item's link, e.g.
+// " - A fast **thing**." becomes "A fast thing.".
+func linkDescription(s string) string {
+	s = mdLink.ReplaceAllString(s, "$1")
+	s = mdInline.ReplaceAllString(s, "")
+	s = strings.TrimLeft(s, " \t-–—:|")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// githubStar is the subset of a GitHub repository object Import reads and
+// Export writes.
+type githubStar struct {
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	Owner    struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	HTMLURL     string   `json:"html_url"`
+	Description string   `json:"description"`
+	Homepage    string   `json:"homepage"`
+	Language    string   `json:"language"`
+	Topics      []string `json:"topics"`
+	Stars       int      `json:"stargazers_count"`
+	Forks       int      `json:"forks_count"`
+	Archived    bool     `json:"archived"`
+	License     *struct {
+		SPDXID string `json:"spdx_id"`
+	} `json:"license"`
+}
+
+func readGitHubStars(r io.Reader) ([]Repo, error) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, err
+	}
+	out := make([]Repo, 0, len(items))
+	for _, raw := range items {
+		// The star+json media type wraps each repo with its starred_at.
+		var wrapped struct {
+			Repo *githubStar `json:"repo"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return nil, err
+		}
+		star := wrapped.Repo
+		if star == nil {
+			star = &githubStar{}
+			if err := json.Unmarshal(raw, star); err != nil {
+				return nil, err
+			}
+		}
+		repo := Repo{
+			URL:         star.HTMLURL,
+			Description: star.Description,
+			Homepage:    star.Homepage,
+			Language:    star.Language,
+			Topics:      star.Topics,
+			Stars:       star.Stars,
+			Forks:       star.Forks,
+			Archived:    star.Archived,
+		}
+		if star.License != nil && star.License.SPDXID != "NOASSERTION" {
+			repo.License = star.License.SPDXID
+		}
+		out = append(out, repo)
+	}
+	return out, nil
+}
+
+// Export writes every repo, ordered by name, as a repo list Import reads
+// back. YAML and JSON keep every field Import reads. Markdown keeps names,
+// descriptions and first tags, putting each repo under the headings of its
+// first tag. GitHub stars keep the forge metadata but not names, tags or
+// notes, so re-imported repos are named after the forge repo.
+func (s *ReposStore) Export(ctx context.Context, w io.Writer, format RepoListFormat) error {
+	repos, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+
+	switch format {
+	case FormatYAML, FormatJSON:
+		m := Manifest{Version: 1, Repos: make([]ManifestRepo, 0, len(repos))}
+		for _, r := range repos {
+			m.Repos = append(m.Repos, ManifestRepo{
+				URL:         r.URL,
+				Name:        r.Name,
+				Description: r.Description,
+				Language:    r.Language,
+				Topics:      r.Topics,
+				Tags:        r.Tags,
+				Notes:       r.Notes,
+			})
+		}
+		if format == FormatJSON {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(m)
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatMarkdown:
+		return writeAwesomeList(w, repos)
+	case FormatGitHubStars:
+		stars := make([]githubStar, 0, len(repos))
+		for _, r := range repos {
+			star := githubStar{
+				Name:        r.Repo,
+				FullName:    r.Owner + "/" + r.Repo,
+				HTMLURL:     r.URL,
+				Description: r.Description,
+				Homepage:    r.Homepage,
+				Language:    r.Language,
+				Topics:      r.Topics,
+				Stars:       r.Stars,
+				Forks:       r.Forks,
+				Archived:    r.Archived,
+			}
+			star.Owner.Login = r.Owner
+			if parsed, err := git.ParseGitURL(r.URL); err == nil {
+				star.HTMLURL = parsed.WebURL()
+			}
+			if r.License != "" {
+				star.License = &struct {
+					SPDXID string `json:"spdx_id"`
+				}{SPDXID: r.License}
+			}
+			stars = append(stars, star)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stars)
+	default:
+		return fmt.Errorf("unknown repo list format %q", format)
+	}
+}
+
+func writeAwesomeList(w io.Writer, repos []Repo) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Repositories")
+
+	sections := make(map[string][]Repo)
+	for _, r := range repos {
+		key := ""
+		if len(r.Tags) > 0 {
+			key = r.Tags[0]
+		}
+		sections[key] = append(sections[key], r)
+	}
+	keys := make([][]string, 0, len(sections))
+	for k := range sections {
+		var path []string
+		if k != "" {
+			path = strings.Split(k, "/")
+		}
+		keys = append(keys, path)
+	}
+	// Sort by segment so a section's subsections follow it directly: "a/b",
+	// "a/b/d", "a/b-c" rather than "a/b", "a/b-c", "a/b/d".
+	sort.Slice(keys, func(i, j int) bool { return slices.Compare(keys[i], keys[j]) < 0 })
+
+	var prev []string
+	for _, path := range keys {
+		// Only open the headings that differ from the previous section.
+		common := 0
+		for common < len(path) && common < len(prev) && path[common] == prev[common] {
+			common++
+		}
+		for i := common; i < len(path); i++ {
+			fmt.Fprintf(bw, "\n%s %s\n", strings.Repeat("#", i+2), path[i])
+		}
+		prev = path
+		fmt.Fprintln(bw)
+		for _, r := range sections[strings.Join(path, "/")] {
+			url := r.URL
+			if parsed, err := git.ParseGitURL(r.URL); err == nil {
+				url = parsed.WebURL()
+			}
+			line := fmt.Sprintf("- [%s](%s)", r.Name, url)
+			if r.Description != "" {
+				line += " - " + strings.Join(strings.Fields(r.Description), " ")
+			}
+			fmt.Fprintln(bw, line)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourorg/arc-sdk/git"
+	"github.com/yourorg/arc-sdk/utils"
+)
+
+// frecencyWeights scale a repo's open count by how long ago it was last
+// opened; opens older than the last bucket count frecencyOldWeight.
+var frecencyWeights = []struct {
+	within int64
+	weight float64
+}{
+	{within: 3600, weight: 4},
+	{within: 86400, weight: 2},
+	{within: 7 * 86400, weight: 0.5},
+}
+
+const frecencyOldWeight = 0.25
+
+// Frecency scores how much a repo is used: its open count weighted by the
+// recency of the last open (×4 within the hour, ×2 within the day, ×½
+// within the week, ×¼ after). Repos never opened score 0.
+func Frecency(accessCount int, lastOpenedAt int64, now time.Time) float64 {
+	if accessCount <= 0 || lastOpenedAt <= 0 {
+		return 0
+	}
+	age := now.Unix() - lastOpenedAt
+	for _, w := range frecencyWeights {
+		if age < w.within {
+			return float64(accessCount) * w.weight
+		}
+	}
+	return float64(accessCount) * frecencyOldWeight
+}
+
+// frecencyExpr is Frecency in SQL as of the Unix time now, for SortFrecency.
+func frecencyExpr(now int64) string {
+	var b strings.Builder
+	b.WriteString("(COALESCE(r.access_count, 0) * CASE WHEN COALESCE(r.last_opened_at, 0) = 0 THEN 0")
+	for _, w := range frecencyWeights {
+		fmt.Fprintf(&b, " WHEN %d - r.last_opened_at < %d THEN %g", now, w.within, w.weight)
+	}
+	fmt.Fprintf(&b, " ELSE %g END)", frecencyOldWeight)
+	return b.String()
+}
+
+// RepoScore is a repo with a ranking score; higher ranks first.
+type RepoScore struct {
+	Repo  Repo
+	Score float64
+}
+
+// UsageOptions configures the usage queries.
+type UsageOptions struct {
+	// Now is when frecency is computed; zero means time.Now().
+	Now time.Time
+	// Limit caps the results, per group for MostUsed; 0 returns all.
+	Limit int
+}
+
+func (o UsageOptions) now() time.Time {
+	if o.Now.IsZero() {
+		return time.Now()
+	}
+	return o.Now
+}
+
+// Frecent returns the repos that have been opened, most frecent first.
+func (s *ReposStore) Frecent(ctx context.Context, opts UsageOptions) ([]RepoScore, error) {
+	repos, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := opts.now()
+	var out []RepoScore
+	for _, r := range repos {
+		if score := Frecency(r.AccessCount, r.LastOpenedAt, now); score > 0 {
+			out = append(out, RepoScore{Repo: r, Score: score})
+		}
+	}
+	sortScores(out)
+	return limitScores(out, opts.Limit), nil
+}
+
+// ArchiveCandidates returns repos never opened, added before cutoff and
+// without commits since (as far as known), oldest first.
+func (s *ReposStore) ArchiveCandidates(ctx context.Context, cutoff int64) ([]Repo, error) {
+	rows, err := s.DB.QueryContext(ctx, repoSelect+`
+		WHERE COALESCE(r.access_count, 0) = 0
+		  AND COALESCE(r.last_opened_at, 0) = 0
+		  AND r.added_at < ?
+		  AND COALESCE(r.last_commit_at, 0) < ?
+		ORDER BY r.added_at ASC, r.name ASC
+	`, cutoff, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return s.scanRepos(rows)
+}
+
+// UsageDimension is what MostUsed groups repos by.
+type UsageDimension string
+
+const (
+	UsageByTag      UsageDimension = "tag"
+	UsageByLanguage UsageDimension = "language"
+)
+
+// UsageGroup is the usage of the repos sharing one tag or language.
+type UsageGroup struct {
+	Key string
+	// Opens is the group's total open count.
+	Opens int
+	// Score is the group's total frecency.
+	Score float64
+	// Repos are the group's opened repos, most frecent first.
+	Repos []RepoScore
+}
+
+// MostUsed groups opened repos by tag or language. Groups are ordered by
+// total frecency; a repo with several tags counts towards each. Untagged
+// repos and repos of unknown language are left out.
+func (s *ReposStore) MostUsed(ctx context.Context, by UsageDimension, opts UsageOptions) ([]UsageGroup, error) {
+	if by != UsageByTag && by != UsageByLanguage {
+		return nil, fmt.Errorf("unknown usage dimension %q", by)
+	}
+	scores, err := s.Frecent(ctx, UsageOptions{Now: opts.Now})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*UsageGroup)
+	for _, sc := range scores {
+		keys := sc.Repo.Tags
+		if by == UsageByLanguage {
+			keys = nil
+			if sc.Repo.Language != "" {
+				keys = []string{sc.Repo.Language}
+			}
+		}
+		for _, key := range keys {
+			g, ok := groups[key]
+			if !ok {
+				g = &UsageGroup{Key: key}
+				groups[key] = g
+			}
+			g.Opens += sc.Repo.AccessCount
+			g.Score += sc.Score
+			g.Repos = append(g.Repos, sc)
+		}
+	}
+
+	out := make([]UsageGroup, 0, len(groups))
+	for _, g := range groups {
+		// scores is sorted, so each group's repos already are.
+		g.Repos = limitScores(g.Repos, opts.Limit)
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out, nil
+}
+
+// Jump ranks the repos whose name, or owner/repo, fuzzily matches query, for
+// jumping to a repo by a few typed characters. The score is the match
+// quality (see utils.FuzzyScore) times 1 + frecency, so among similar matches
+// the repos used most win. An empty query ranks by frecency alone, like
+// Frecent. Callers should RecordOpen the repo the user picks.
+func (s *ReposStore) Jump(ctx context.Context, query string, opts UsageOptions) ([]RepoScore, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return s.Frecent(ctx, opts)
+	}
+	repos, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := opts.now()
+	var out []RepoScore
+	for _, r := range repos {
+		match, ok := utils.FuzzyScore(query, r.Name)
+		owner, name := r.Owner, r.Repo
+		if owner == "" || name == "" {
+			if parsed, err := git.ParseGitURL(r.URL); err == nil {
+				owner, name = parsed.Owner, parsed.Name
+			}
+		}
+		if owner != "" && name != "" {
+			if m, found := utils.FuzzyScore(query, owner+"/"+name); found && m > match {
+				match, ok = m, true
+			}
+		}
+		if !ok {
+			continue
+		}
+		score := match * (1 + Frecency(r.AccessCount, r.LastOpenedAt, now))
+		out = append(out, RepoScore{Repo: r, Score: score})
+	}
+	sortScores(out)
+	return limitScores(out, opts.Limit), nil
+}
+
+// sortScores orders scores best first, then by name.
+func sortScores(scores []RepoScore) {
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Repo.Name < scores[j].Repo.Name
+	})
+}
+
+func limitScores(scores []RepoScore, limit int) []RepoScore {
+	if limit > 0 && len(scores) > limit {
+		return scores[:limit]
+	}
+	return scores
+}
@@ -9,6 +9,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/yourorg/arc-sdk/db"
+	"github.com/yourorg/arc-sdk/git"
 )
 
 // Repo represents an external repository stored in the database.
@@ -50,6 +53,9 @@ type Repo struct {
 	LastAnalyzedAt   int64
 	LastAnalysisType string
 	LastAnalyzedBy   string
+
+	// EnrichedAt is when forge metadata was last fetched (see package enrich).
+	EnrichedAt int64
 }
 
 // RepoAnalysis represents a recorded repository analysis entry.
@@ -80,15 +86,15 @@ type SearchOptions struct {
 
 // ReposStore manages repository data in the database.
 type ReposStore struct {
-	DB *sql.DB
+	DB db.Querier
 }
 
 // NewReposStore creates a new ReposStore.
-func NewReposStore(db *sql.DB) *ReposStore {
-	return &ReposStore{DB: db}
+func NewReposStore(q db.Querier) *ReposStore {
+	return &ReposStore{DB: q}
 }
 
-// Upsert inserts or updates a repo record.
+// Upsert inserts or updates a repo record, replacing its tags with repo.Tags.
 func (s *ReposStore) Upsert(ctx context.Context, repo Repo) error {
 	topicsJSON, err := json.Marshal(repo.Topics)
 	if err != nil {
@@ -99,17 +105,28 @@ func (s *ReposStore) Upsert(ctx context.Context, repo Repo) error {
 		return fmt.Errorf("marshal tags: %w", err)
 	}
 
-	_, err = s.DB.ExecContext(ctx, `
+	return db.InTx(ctx, s.DB, func(tx *sql.Tx) error {
+		if err := s.upsertRow(ctx, tx, repo, string(topicsJSON), string(tagsJSON)); err != nil {
+			return err
+		}
+		return NewTagsStore(tx).Set(ctx, RepoTags, repo.Name, repo.Tags)
+	})
+}
+
+func (s *ReposStore) upsertRow(ctx context.Context, tx *sql.Tx, repo Repo, topicsJSON, tagsJSON string) error {
+	_, err := tx.ExecContext(ctx, `
 		INSERT INTO external_repos(
 			name, url, description, platform, owner, repo,
 			cloned, clone_path, cloned_at, updated_at, shallow,
 			language, topics, stars, forks, license, homepage,
 			added_at, added_by, last_opened_at, access_count,
 			archived, tags, notes,
-			default_branch, commit_count, last_commit_at
-		) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+			default_branch, commit_count, last_commit_at,
+			canonical_url
+		) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
 		ON CONFLICT(name) DO UPDATE SET
 			url=excluded.url,
+			canonical_url=excluded.canonical_url,
 			description=excluded.description,
 			platform=excluded.platform,
 			owner=excluded.owner,
@@ -137,42 +154,35 @@ func (s *ReposStore) Upsert(ctx context.Context, repo Repo) error {
 	`,
 		repo.Name, repo.URL, repo.Description, repo.Platform, repo.Owner, repo.Repo,
 		boolToInt(repo.Cloned), repo.ClonePath, repo.ClonedAt, repo.UpdatedAt, boolToInt(repo.Shallow),
-		repo.Language, string(topicsJSON), repo.Stars, repo.Forks, repo.License, repo.Homepage,
+		repo.Language, topicsJSON, repo.Stars, repo.Forks, repo.License, repo.Homepage,
 		repo.AddedAt, repo.AddedBy, repo.LastOpenedAt, repo.AccessCount,
-		boolToInt(repo.Archived), string(tagsJSON), repo.Notes,
+		boolToInt(repo.Archived), tagsJSON, repo.Notes,
 		repo.DefaultBranch, repo.CommitCount, repo.LastCommitAt,
+		git.CanonicalURL(repo.URL),
 	)
 	return err
 }
 
 // Get retrieves a repo by name.
 func (s *ReposStore) Get(ctx context.Context, name string) (*Repo, error) {
-	row := s.DB.QueryRowContext(ctx, `
-		SELECT name, url, description, platform, owner, repo,
-		       cloned, clone_path, cloned_at, updated_at, shallow,
-		       language, topics, stars, forks, license, homepage,
-		       added_at, added_by, last_opened_at, access_count,
-		       archived, tags, notes,
-		       default_branch, commit_count, last_commit_at
-		FROM external_repos
-		WHERE name = ?
+	row := s.DB.QueryRowContext(ctx, repoSelect+`
+		WHERE r.name = ?
 	`, name)
 
 	return s.scanRepo(row)
 }
 
-// GetByURL retrieves a repo by URL.
+// GetByURL retrieves a repo by URL. Other spellings of the URL (see
+// git.CanonicalURL) match too, as do locations the repo was moved from. An
+// exact match wins over a canonical one.
 func (s *ReposStore) GetByURL(ctx context.Context, url string) (*Repo, error) {
-	row := s.DB.QueryRowContext(ctx, `
-		SELECT name, url, description, platform, owner, repo,
-		       cloned, clone_path, cloned_at, updated_at, shallow,
-		       language, topics, stars, forks, license, homepage,
-		       added_at, added_by, last_opened_at, access_count,
-		       archived, tags, notes,
-		       default_branch, commit_count, last_commit_at
-		FROM external_repos
-		WHERE url = ?
-	`, url)
+	canonical := git.CanonicalURL(url)
+	row := s.DB.QueryRowContext(ctx, repoSelect+`
+		WHERE r.url = ? OR r.canonical_url = ?
+		   OR r.name = (SELECT repo_name FROM repo_redirects WHERE canonical_url = ?)
+		ORDER BY r.url = ? DESC, r.canonical_url = ? DESC, r.added_at, r.name
+		LIMIT 1
+	`, url, canonical, canonical, url, canonical)
 
 	return s.scanRepo(row)
 }
@@ -185,15 +195,8 @@ func (s *ReposStore) Delete(ctx context.Context, name string) error {
 
 // List returns all repos.
 func (s *ReposStore) List(ctx context.Context) ([]Repo, error) {
-	rows, err := s.DB.QueryContext(ctx, `
-		SELECT name, url, description, platform, owner, repo,
-		       cloned, clone_path, cloned_at, updated_at, shallow,
-		       language, topics, stars, forks, license, homepage,
-		       added_at, added_by, last_opened_at, access_count,
-		       archived, tags, notes,
-		       default_branch, commit_count, last_commit_at
-		FROM external_repos
-		ORDER BY added_at DESC
+	rows, err := s.DB.QueryContext(ctx, repoSelect+`
+		ORDER BY r.added_at DESC
 	`)
 	if err != nil {
 		return nil, err
@@ -205,16 +208,9 @@ func (s *ReposStore) List(ctx context.Context) ([]Repo, error) {
 
 // ListCloned returns only cloned repos.
 func (s *ReposStore) ListCloned(ctx context.Context) ([]Repo, error) {
-	rows, err := s.DB.QueryContext(ctx, `
-		SELECT name, url, description, platform, owner, repo,
-		       cloned, clone_path, cloned_at, updated_at, shallow,
-		       language, topics, stars, forks, license, homepage,
-		       added_at, added_by, last_opened_at, access_count,
-		       archived, tags, notes,
-		       default_branch, commit_count, last_commit_at
-		FROM external_repos
-		WHERE cloned = 1
-		ORDER BY added_at DESC
+	rows, err := s.DB.QueryContext(ctx, repoSelect+`
+		WHERE r.cloned = 1
+		ORDER BY r.added_at DESC
 	`)
 	if err != nil {
 		return nil, err
@@ -224,6 +220,26 @@ func (s *ReposStore) ListCloned(ctx context.Context) ([]Repo, error) {
 	return s.scanRepos(rows)
 }
 
+// NotAnalyzedSince returns repos with no successful analysis (of
+// analysisType, if set) at or after the Unix time cutoff, including repos
+// never analyzed, least recently analyzed first.
+func (s *ReposStore) NotAnalyzedSince(ctx context.Context, cutoff int64, analysisType string) ([]Repo, error) {
+	rows, err := s.DB.QueryContext(ctx, repoSelect+`
+		WHERE NOT EXISTS (
+			SELECT 1 FROM repo_analysis ra
+			WHERE ra.repo_name = r.name AND ra.success = 1 AND ra.analyzed_at >= ?
+			  AND (? = '' OR ra.analysis_type = ?)
+		)
+		ORDER BY `+repoLastAnalyzedAt+` NULLS FIRST, r.name
+	`, cutoff, analysisType, analysisType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return s.scanRepos(rows)
+}
+
 // Count returns the total number of repos in the database.
 func (s *ReposStore) Count(ctx context.Context) (int, error) {
 	var count int
@@ -249,6 +265,27 @@ func (s *ReposStore) MarkCloned(ctx context.Context, name string, path string) e
 	return err
 }
 
+// MarkNotCloned records that a repo has no clone on disk.
+func (s *ReposStore) MarkNotCloned(ctx context.Context, name string) error {
+	_, err := s.DB.ExecContext(ctx, `
+		UPDATE external_repos
+		SET cloned = 0, clone_path = NULL, shallow = 0
+		WHERE name = ?
+	`, name)
+	return err
+}
+
+// SetClonePath records an existing clone of a repo found at path, keeping
+// cloned_at if it was already set.
+func (s *ReposStore) SetClonePath(ctx context.Context, name string, path string) error {
+	_, err := s.DB.ExecContext(ctx, `
+		UPDATE external_repos
+		SET cloned = 1, clone_path = ?, cloned_at = COALESCE(NULLIF(cloned_at, 0), ?)
+		WHERE name = ?
+	`, path, time.Now().Unix(), name)
+	return err
+}
+
 // MarkUpdated updates the updated_at timestamp for a repo.
 func (s *ReposStore) MarkUpdated(ctx context.Context, name string) error {
 	now := time.Now().Unix()
@@ -260,6 +297,52 @@ func (s *ReposStore) MarkUpdated(ctx context.Context, name string) error {
 	return err
 }
 
+// UpdateFromClone inspects a repo's clone with git.Inspect and stores what it
+// finds. dir is the clone to inspect; "" uses the repo's ClonePath. Git facts
+// (default branch, commit count, last commit, shallowness) replace stored
+// values, except that a shallow clone's partial commit count is ignored.
+// Language, license and description are guesses, so they only fill fields
+// that are empty.
+func (s *ReposStore) UpdateFromClone(ctx context.Context, name, dir string) (*git.Inspection, error) {
+	if dir == "" {
+		repo, err := s.Get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if repo.ClonePath == "" {
+			return nil, fmt.Errorf("repo %s has no clone path", name)
+		}
+		dir = repo.ClonePath
+	}
+	in, err := git.Inspect(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var commitCount any
+	if !in.Shallow {
+		commitCount = in.CommitCount
+	}
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE external_repos SET
+			default_branch = COALESCE(NULLIF(?, ''), default_branch),
+			commit_count = COALESCE(?, commit_count),
+			last_commit_at = COALESCE(NULLIF(?, 0), last_commit_at),
+			shallow = ?,
+			language = COALESCE(NULLIF(language, ''), NULLIF(?, '')),
+			license = COALESCE(NULLIF(license, ''), NULLIF(?, '')),
+			description = COALESCE(NULLIF(description, ''), NULLIF(?, ''), description)
+		WHERE name = ?
+	`, in.DefaultBranch, commitCount, in.LastCommitAt, boolToInt(in.Shallow), in.Language, in.License, in.Description, name)
+	if err != nil {
+		return nil, fmt.Errorf("update %s from clone: %w", name, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return in, nil
+}
+
 // RecordOpen increments access count and updates last_opened_at.
 func (s *ReposStore) RecordOpen(ctx context.Context, name string) error {
 	now := time.Now().Unix()
@@ -272,15 +355,9 @@ func (s *ReposStore) RecordOpen(ctx context.Context, name string) error {
 }
 
 // Search performs full-text search on repos using FTS5 with optional filters.
+// Query supports more filters, other orders and paging.
 func (s *ReposStore) Search(ctx context.Context, query string, opts SearchOptions) ([]Repo, error) {
-	sqlQuery := `
-		SELECT r.name, r.url, r.description, r.platform, r.owner, r.repo,
-		       r.cloned, r.clone_path, r.cloned_at, r.updated_at, r.shallow,
-		       r.language, r.topics, r.stars, r.forks, r.license, r.homepage,
-		       r.added_at, r.added_by, r.last_opened_at, r.access_count,
-		       r.archived, r.tags, r.notes,
-		       r.default_branch, r.commit_count, r.last_commit_at
-		FROM external_repos r
+	sqlQuery := repoSelect + `
 		JOIN external_repos_fts fts ON fts.rowid = r.rowid
 		WHERE external_repos_fts MATCH ?`
 
@@ -315,76 +392,32 @@ func (s *ReposStore) Search(ctx context.Context, query string, opts SearchOption
 	return s.scanRepos(rows)
 }
 
-// AddTag adds a tag to a repo's tags array.
+// AddTag adds a tag to a repo's tags.
 func (s *ReposStore) AddTag(ctx context.Context, name string, tag string) error {
-	repo, err := s.Get(ctx, name)
-	if err != nil {
-		return fmt.Errorf("get repo: %w", err)
-	}
-
-	for _, t := range repo.Tags {
-		if t == tag {
-			return nil
-		}
-	}
-
-	repo.Tags = append(repo.Tags, tag)
-	tagsJSON, err := json.Marshal(repo.Tags)
-	if err != nil {
-		return fmt.Errorf("marshal tags: %w", err)
-	}
-
-	_, err = s.DB.ExecContext(ctx, `UPDATE external_repos SET tags = ? WHERE name = ?`, string(tagsJSON), name)
-	return err
+	return NewTagsStore(s.DB).Tag(ctx, RepoTags, []string{name}, tag)
 }
 
-// RemoveTag removes a tag from a repo's tags array.
+// RemoveTag removes a tag from a repo's tags.
 func (s *ReposStore) RemoveTag(ctx context.Context, name string, tag string) error {
-	repo, err := s.Get(ctx, name)
-	if err != nil {
-		return fmt.Errorf("get repo: %w", err)
-	}
-
-	newTags := []string{}
-	for _, t := range repo.Tags {
-		if t != tag {
-			newTags = append(newTags, t)
-		}
-	}
-
-	tagsJSON, err := json.Marshal(newTags)
-	if err != nil {
-		return fmt.Errorf("marshal tags: %w", err)
-	}
-
-	_, err = s.DB.ExecContext(ctx, `UPDATE external_repos SET tags = ? WHERE name = ?`, string(tagsJSON), name)
-	return err
+	return NewTagsStore(s.DB).Untag(ctx, RepoTags, []string{name}, tag)
 }
 
 // SetTags replaces all tags for a repo.
 func (s *ReposStore) SetTags(ctx context.Context, name string, tags []string) error {
-	tagsJSON, err := json.Marshal(tags)
-	if err != nil {
-		return fmt.Errorf("marshal tags: %w", err)
-	}
-
-	_, err = s.DB.ExecContext(ctx, `UPDATE external_repos SET tags = ? WHERE name = ?`, string(tagsJSON), name)
-	return err
+	return NewTagsStore(s.DB).Set(ctx, RepoTags, name, tags)
 }
 
-// FindByTag returns all repos that have a specific tag.
+// FindByTag returns all repos that have a specific tag or one of its
+// descendants ("lang" matches "lang/go").
 func (s *ReposStore) FindByTag(ctx context.Context, tag string) ([]Repo, error) {
-	rows, err := s.DB.QueryContext(ctx, `
-		SELECT name, url, description, platform, owner, repo,
-		       cloned, clone_path, cloned_at, updated_at, shallow,
-		       language, topics, stars, forks, license, homepage,
-		       added_at, added_by, last_opened_at, access_count,
-		       archived, tags, notes,
-		       default_branch, commit_count, last_commit_at
-		FROM external_repos
-		WHERE tags LIKE ?
-		ORDER BY stars DESC
-	`, "%\""+tag+"\"%")
+	cond, args := tagMatch("t.name", NormalizeTag(tag))
+	rows, err := s.DB.QueryContext(ctx, repoSelect+`
+		WHERE EXISTS (
+			SELECT 1 FROM repo_tags rt JOIN tags t ON t.id = rt.tag_id
+			WHERE rt.repo_name = r.name AND `+cond+`
+		)
+		ORDER BY r.stars DESC
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -393,9 +426,12 @@ func (s *ReposStore) FindByTag(ctx context.Context, tag string) ([]Repo, error)
 	return s.scanRepos(rows)
 }
 
-// ListTags returns all unique tags with their usage counts.
+// ListTags returns all tags in use on repos with their usage counts.
 func (s *ReposStore) ListTags(ctx context.Context) (map[string]int, error) {
-	rows, err := s.DB.QueryContext(ctx, `SELECT tags FROM external_repos WHERE tags IS NOT NULL AND tags != '[]' AND tags != 'null'`)
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT t.name, COUNT(*) FROM repo_tags rt JOIN tags t ON t.id = rt.tag_id
+		GROUP BY t.name
+	`)
 	if err != nil {
 		return nil, err
 	}
@@ -403,19 +439,12 @@ func (s *ReposStore) ListTags(ctx context.Context) (map[string]int, error) {
 
 	tagCounts := make(map[string]int)
 	for rows.Next() {
-		var tagsJSON string
-		if err := rows.Scan(&tagsJSON); err != nil {
+		var tag string
+		var n int
+		if err := rows.Scan(&tag, &n); err != nil {
 			return nil, err
 		}
-
-		var tags []string
-		if err := json.Unmarshal([]byte(tagsJSON), &tags); err != nil {
-			continue
-		}
-
-		for _, tag := range tags {
-			tagCounts[tag]++
-		}
+		tagCounts[tag] = n
 	}
 
 	return tagCounts, rows.Err()
@@ -460,21 +489,77 @@ func (s *ReposStore) GetLanguageBreakdown(ctx context.Context) ([]LanguageStats,
 	return stats, rows.Err()
 }
 
+// repoAnalysisCount and repoLastAnalyzedAt summarise the analyses of the
+// repo aliased r. Correlated subqueries use the repo_name index, where
+// joining repo_analysis_summary would aggregate every analysis for each
+// single-repo lookup.
+const (
+	repoAnalysisCount  = `(SELECT COUNT(*) FROM repo_analysis ra WHERE ra.repo_name = r.name)`
+	repoLastAnalyzedAt = `(SELECT MAX(ra.analyzed_at) FROM repo_analysis ra WHERE ra.repo_name = r.name)`
+	repoLatestAnalysis = `FROM repo_analysis ra WHERE ra.repo_name = r.name ORDER BY ra.analyzed_at DESC, ra.id DESC LIMIT 1`
+)
+
+// repoColumns lists every Repo column of external_repos (aliased r), its
+// analysis summary and enrichment state (aliased re), in the order
+// scanRepoFrom expects.
+const repoColumns = `
+		       r.name, r.url, r.description, r.platform, r.owner, r.repo,
+		       r.cloned, r.clone_path, r.cloned_at, r.updated_at, r.shallow,
+		       r.language, r.topics, r.stars, r.forks, r.license, r.homepage,
+		       r.added_at, r.added_by, r.last_opened_at, r.access_count,
+		       r.archived, r.tags, r.notes,
+		       r.default_branch, r.commit_count, r.last_commit_at,
+		       ` + repoAnalysisCount + `, ` + repoLastAnalyzedAt + `,
+		       (SELECT ra.analysis_type ` + repoLatestAnalysis + `),
+		       (SELECT ra.analyzed_by ` + repoLatestAnalysis + `),
+		       re.enriched_at`
+
+// repoFrom joins the enrichment state onto external_repos.
+const repoFrom = `
+		FROM external_repos r
+		LEFT JOIN repo_enrichment re ON re.repo_name = r.name`
+
+// repoSelect selects every Repo column; append WHERE/ORDER BY clauses.
+const repoSelect = `SELECT` + repoColumns + repoFrom
+
 func (s *ReposStore) scanRepo(row *sql.Row) (*Repo, error) {
+	return scanRepoFrom(row)
+}
+
+func (s *ReposStore) scanRepos(rows *sql.Rows) ([]Repo, error) {
+	var repos []Repo
+	for rows.Next() {
+		r, err := scanRepoFrom(rows)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, *r)
+	}
+
+	return repos, rows.Err()
+}
+
+// scanRepoFrom scans one row selected by repoSelect, followed by any extra
+// columns into extra.
+func scanRepoFrom(row interface{ Scan(dest ...any) error }, extra ...any) (*Repo, error) {
 	var r Repo
 	var clonedInt, shallowInt, archivedInt int
 	var topicsJSON, tagsJSON string
-	var clonedAt, updatedAt, lastOpenedAt, lastCommitAt sql.NullInt64
+	var clonedAt, updatedAt, lastOpenedAt, lastCommitAt, lastAnalyzedAt, enrichedAt sql.NullInt64
 	var clonePath, language, license, homepage, addedBy, notes, defaultBranch sql.NullString
+	var lastAnalysisType, lastAnalyzedBy sql.NullString
 
-	err := row.Scan(
+	dest := []any{
 		&r.Name, &r.URL, &r.Description, &r.Platform, &r.Owner, &r.Repo,
 		&clonedInt, &clonePath, &clonedAt, &updatedAt, &shallowInt,
 		&language, &topicsJSON, &r.Stars, &r.Forks, &license, &homepage,
 		&r.AddedAt, &addedBy, &lastOpenedAt, &r.AccessCount,
 		&archivedInt, &tagsJSON, &notes,
 		&defaultBranch, &r.CommitCount, &lastCommitAt,
-	)
+		&r.AnalysisCount, &lastAnalyzedAt, &lastAnalysisType, &lastAnalyzedBy,
+		&enrichedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -516,6 +601,10 @@ func (s *ReposStore) scanRepo(row *sql.Row) (*Repo, error) {
 	if lastCommitAt.Valid {
 		r.LastCommitAt = lastCommitAt.Int64
 	}
+	r.LastAnalyzedAt = lastAnalyzedAt.Int64
+	r.LastAnalysisType = lastAnalysisType.String
+	r.LastAnalyzedBy = lastAnalyzedBy.String
+	r.EnrichedAt = enrichedAt.Int64
 
 	if topicsJSON != "" && topicsJSON != "null" {
 		_ = json.Unmarshal([]byte(topicsJSON), &r.Topics)
@@ -527,78 +616,6 @@ func (s *ReposStore) scanRepo(row *sql.Row) (*Repo, error) {
 	return &r, nil
 }
 
-func (s *ReposStore) scanRepos(rows *sql.Rows) ([]Repo, error) {
-	var repos []Repo
-	for rows.Next() {
-		var r Repo
-		var clonedInt, shallowInt, archivedInt int
-		var topicsJSON, tagsJSON string
-		var clonedAt, updatedAt, lastOpenedAt, lastCommitAt sql.NullInt64
-		var clonePath, language, license, homepage, addedBy, notes, defaultBranch sql.NullString
-
-		err := rows.Scan(
-			&r.Name, &r.URL, &r.Description, &r.Platform, &r.Owner, &r.Repo,
-			&clonedInt, &clonePath, &clonedAt, &updatedAt, &shallowInt,
-			&language, &topicsJSON, &r.Stars, &r.Forks, &license, &homepage,
-			&r.AddedAt, &addedBy, &lastOpenedAt, &r.AccessCount,
-			&archivedInt, &tagsJSON, &notes,
-			&defaultBranch, &r.CommitCount, &lastCommitAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		r.Cloned = clonedInt != 0
-		r.Shallow = shallowInt != 0
-		r.Archived = archivedInt != 0
-
-		if clonePath.Valid {
-			r.ClonePath = clonePath.String
-		}
-		if clonedAt.Valid {
-			r.ClonedAt = clonedAt.Int64
-		}
-		if updatedAt.Valid {
-			r.UpdatedAt = updatedAt.Int64
-		}
-		if language.Valid {
-			r.Language = language.String
-		}
-		if license.Valid {
-			r.License = license.String
-		}
-		if homepage.Valid {
-			r.Homepage = homepage.String
-		}
-		if addedBy.Valid {
-			r.AddedBy = addedBy.String
-		}
-		if lastOpenedAt.Valid {
-			r.LastOpenedAt = lastOpenedAt.Int64
-		}
-		if notes.Valid {
-			r.Notes = notes.String
-		}
-		if defaultBranch.Valid {
-			r.DefaultBranch = defaultBranch.String
-		}
-		if lastCommitAt.Valid {
-			r.LastCommitAt = lastCommitAt.Int64
-		}
-
-		if topicsJSON != "" && topicsJSON != "null" {
-			_ = json.Unmarshal([]byte(topicsJSON), &r.Topics)
-		}
-		if tagsJSON != "" && tagsJSON != "null" {
-			_ = json.Unmarshal([]byte(tagsJSON), &r.Tags)
-		}
-
-		repos = append(repos, r)
-	}
-
-	return repos, rows.Err()
-}
-
 func boolToInt(b bool) int {
 	if b {
 		return 1
@@ -0,0 +1,371 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned by ReposStore.Query for a cursor that is
+// malformed or was issued for a different sort.
+var ErrInvalidCursor = errors.New("store: invalid cursor")
+
+// RepoSort names the order of RepoQuery results. Any sortable external_repos
+// column may be used by name; SortRelevance orders text matches by bm25.
+type RepoSort string
+
+const (
+	SortRelevance    RepoSort = "relevance"
+	SortName         RepoSort = "name"
+	SortStars        RepoSort = "stars"
+	SortForks        RepoSort = "forks"
+	SortAddedAt      RepoSort = "added_at"
+	SortUpdatedAt    RepoSort = "updated_at"
+	SortLastCommitAt RepoSort = "last_commit_at"
+	SortLastOpenedAt RepoSort = "last_opened_at"
+	SortAccessCount  RepoSort = "access_count"
+	SortLastAnalyzed RepoSort = "last_analyzed_at"
+	SortEnrichedAt   RepoSort = "enriched_at"
+	// SortFrecency orders by Frecency as of RepoQuery.Now; use Desc for most
+	// used first.
+	SortFrecency RepoSort = "frecency"
+)
+
+// sortColumn is how a RepoSort orders rows. NULLs sort as the zero value so
+// keyset comparisons stay total.
+type sortColumn struct {
+	expr    string
+	numeric bool
+}
+
+var repoSortColumns = map[RepoSort]sortColumn{
+	SortRelevance:    {expr: "bm25(external_repos_fts)", numeric: true},
+	SortName:         {expr: "r.name"},
+	"url":            {expr: "r.url"},
+	"description":    {expr: "COALESCE(r.description, '')"},
+	"platform":       {expr: "COALESCE(r.platform, '')"},
+	"owner":          {expr: "COALESCE(r.owner, '')"},
+	"repo":           {expr: "COALESCE(r.repo, '')"},
+	"language":       {expr: "COALESCE(r.language, '')"},
+	"license":        {expr: "COALESCE(r.license, '')"},
+	"added_by":       {expr: "COALESCE(r.added_by, '')"},
+	"default_branch": {expr: "COALESCE(r.default_branch, '')"},
+	SortStars:        {expr: "COALESCE(r.stars, 0)", numeric: true},
+	SortForks:        {expr: "COALESCE(r.forks, 0)", numeric: true},
+	"cloned_at":      {expr: "COALESCE(r.cloned_at, 0)", numeric: true},
+	SortUpdatedAt:    {expr: "COALESCE(r.updated_at, 0)", numeric: true},
+	SortAddedAt:      {expr: "r.added_at", numeric: true},
+	SortLastOpenedAt: {expr: "COALESCE(r.last_opened_at, 0)", numeric: true},
+	SortAccessCount:  {expr: "COALESCE(r.access_count, 0)", numeric: true},
+	"commit_count":   {expr: "COALESCE(r.commit_count, 0)", numeric: true},
+	SortLastCommitAt: {expr: "COALESCE(r.last_commit_at, 0)", numeric: true},
+	SortLastAnalyzed: {expr: "COALESCE(" + repoLastAnalyzedAt + ", 0)", numeric: true},
+	"analysis_count": {expr: repoAnalysisCount, numeric: true},
+	SortEnrichedAt:   {expr: "COALESCE(re.enriched_at, 0)", numeric: true},
+	SortFrecency:     {numeric: true}, // Query fills in expr for its time.
+}
+
+// TimeRange bounds a Unix-time column: Since is inclusive, Until exclusive,
+// and zero leaves that side open.
+type TimeRange struct {
+	Since int64
+	Until int64
+}
+
+// RepoQuery selects repos for ReposStore.Query. Zero-valued fields do not
+// filter; list fields match any of their values unless noted.
+type RepoQuery struct {
+	// Text is an FTS5 query over name, description, topics, tags and notes.
+	Text string
+
+	Languages []string
+	Platforms []string
+	Owners    []string
+	Licenses  []string
+	AddedBy   []string
+	// TagsAny matches repos with at least one of the tags, TagsAll repos
+	// with every one of them; a tag also matches its descendants.
+	TagsAny   []string
+	TagsAll   []string
+	TopicsAny []string
+	TopicsAll []string
+	MinStars  int
+	Cloned    *bool
+	Archived  *bool
+
+	Added      TimeRange
+	Updated    TimeRange
+	LastCommit TimeRange
+
+	// Sort defaults to SortRelevance with Text and SortAddedAt otherwise.
+	Sort RepoSort
+	// Desc reverses the order; relevance is always best first.
+	Desc bool
+	// Limit caps the page size; 0 returns every match in one page.
+	Limit int
+	// Cursor continues from RepoPage.NextCursor of a query with the same
+	// filters and sort.
+	Cursor string
+	// Now is when SortFrecency is computed; zero means time.Now(). Later
+	// pages keep the first page's time, so scores do not shift under the
+	// cursor.
+	Now time.Time
+}
+
+// RepoPage is one page of RepoQuery results.
+type RepoPage struct {
+	Repos []Repo
+	// NextCursor fetches the following page; empty on the last one.
+	NextCursor string
+}
+
+// repoCursor is the keyset position after the last row of a page.
+type repoCursor struct {
+	Sort  RepoSort `json:"s"`
+	Desc  bool     `json:"d,omitempty"`
+	Value any      `json:"v"`
+	Name  string   `json:"n"`
+	// Now is the Unix time SortFrecency was computed at.
+	Now int64 `json:"t,omitempty"`
+}
+
+func (c repoCursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeRepoCursor(s string) (repoCursor, error) {
+	var c repoCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Query returns repos matching q, one page at a time. Results are ordered by
+// q.Sort with the repo name breaking ties, which keeps cursors stable while
+// rows are added or removed.
+func (s *ReposStore) Query(ctx context.Context, q RepoQuery) (*RepoPage, error) {
+	sort := q.Sort
+	if sort == "" {
+		sort = SortAddedAt
+		if q.Text != "" {
+			sort = SortRelevance
+		}
+	}
+	col, ok := repoSortColumns[sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", sort)
+	}
+	if sort == SortRelevance && q.Text == "" {
+		return nil, errors.New("relevance sort requires a text query")
+	}
+	// Lower bm25 scores are better, so relevance ascends.
+	desc := q.Desc && sort != SortRelevance
+
+	var cursor *repoCursor
+	if q.Cursor != "" {
+		c, err := decodeRepoCursor(q.Cursor)
+		if err != nil || c.Sort != sort || c.Desc != desc || (sort == SortFrecency && c.Now == 0) {
+			return nil, ErrInvalidCursor
+		}
+		cursor = &c
+	}
+	var now int64
+	if sort == SortFrecency {
+		now = time.Now().Unix()
+		if !q.Now.IsZero() {
+			now = q.Now.Unix()
+		}
+		if cursor != nil {
+			now = cursor.Now
+		}
+		col.expr = frecencyExpr(now)
+	}
+
+	query := `SELECT` + repoColumns + `, ` + col.expr + repoFrom
+	var where []string
+	var args []any
+	if q.Text != "" {
+		query += `
+		JOIN external_repos_fts ON external_repos_fts.rowid = r.rowid`
+		where = append(where, "external_repos_fts MATCH ?")
+		args = append(args, q.Text)
+	}
+
+	in := func(column string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		where = append(where, column+" IN ("+placeholders(len(values))+")")
+		for _, v := range values {
+			args = append(args, v)
+		}
+	}
+	in("r.language", q.Languages)
+	in("r.platform", q.Platforms)
+	in("r.owner", q.Owners)
+	in("r.license", q.Licenses)
+	in("r.added_by", q.AddedBy)
+
+	// Tags match hierarchically: "lang" also matches "lang/go".
+	tagExists := func(tags []string) string {
+		var conds []string
+		for _, tag := range tags {
+			cond, tagArgs := tagMatch("t.name", NormalizeTag(tag))
+			conds = append(conds, cond)
+			args = append(args, tagArgs...)
+		}
+		return "EXISTS (SELECT 1 FROM repo_tags rt JOIN tags t ON t.id = rt.tag_id WHERE rt.repo_name = r.name AND (" + strings.Join(conds, " OR ") + "))"
+	}
+	if len(q.TagsAny) > 0 {
+		where = append(where, tagExists(q.TagsAny))
+	}
+	for _, tag := range q.TagsAll {
+		where = append(where, tagExists([]string{tag}))
+	}
+
+	// topics holds a JSON array; anything else counts as empty.
+	topics := "json_each(CASE WHEN json_valid(r.topics) THEN r.topics ELSE '[]' END)"
+	if len(q.TopicsAny) > 0 {
+		where = append(where, "EXISTS (SELECT 1 FROM "+topics+" WHERE value IN ("+placeholders(len(q.TopicsAny))+"))")
+		for _, v := range q.TopicsAny {
+			args = append(args, v)
+		}
+	}
+	for _, topic := range q.TopicsAll {
+		where = append(where, "EXISTS (SELECT 1 FROM "+topics+" WHERE value = ?)")
+		args = append(args, topic)
+	}
+
+	if q.MinStars > 0 {
+		where = append(where, "r.stars >= ?")
+		args = append(args, q.MinStars)
+	}
+	if q.Cloned != nil {
+		where = append(where, "r.cloned = ?")
+		args = append(args, boolToInt(*q.Cloned))
+	}
+	if q.Archived != nil {
+		where = append(where, "COALESCE(r.archived, 0) = ?")
+		args = append(args, boolToInt(*q.Archived))
+	}
+	timeRange := func(column string, tr TimeRange) {
+		if tr.Since != 0 {
+			where = append(where, column+" >= ?")
+			args = append(args, tr.Since)
+		}
+		if tr.Until != 0 {
+			where = append(where, column+" < ?")
+			args = append(args, tr.Until)
+		}
+	}
+	timeRange("r.added_at", q.Added)
+	timeRange("r.updated_at", q.Updated)
+	timeRange("r.last_commit_at", q.LastCommit)
+
+	if cursor != nil {
+		value, err := cursorValue(cursor.Value, col.numeric)
+		if err != nil {
+			return nil, err
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		if sort == SortName {
+			where = append(where, "r.name "+op+" ?")
+			args = append(args, cursor.Name)
+		} else {
+			where = append(where, "("+col.expr+" "+op+" ? OR ("+col.expr+" = ? AND r.name > ?))")
+			args = append(args, value, value, cursor.Name)
+		}
+	}
+
+	if len(where) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(where, "\n\t\t  AND ")
+	}
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	query += "\n\t\tORDER BY " + col.expr + " " + dir
+	if sort != SortName {
+		query += ", r.name ASC"
+	}
+	if q.Limit > 0 {
+		// One extra row tells whether another page follows.
+		query += " LIMIT ?"
+		args = append(args, q.Limit+1)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &RepoPage{}
+	var last any
+	for rows.Next() {
+		if q.Limit > 0 && len(page.Repos) == q.Limit {
+			page.NextCursor = repoCursor{
+				Sort:  sort,
+				Desc:  desc,
+				Value: last,
+				Name:  page.Repos[len(page.Repos)-1].Name,
+				Now:   now,
+			}.encode()
+			break
+		}
+		var value any
+		r, err := scanRepoFrom(rows, &value)
+		if err != nil {
+			return nil, err
+		}
+		page.Repos = append(page.Repos, *r)
+		last = value
+	}
+	return page, rows.Err()
+}
+
+// cursorValue converts a decoded cursor value back to the sort column's type.
+func cursorValue(v any, numeric bool) (any, error) {
+	if !numeric {
+		s, ok := v.(string)
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		return s, nil
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	if i, err := n.Int64(); err == nil {
+		return i, nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return f, nil
+}
+
+// placeholders returns n comma-separated "?".
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
@@ -6,6 +6,9 @@ package store
 import (
 	"context"
 	"database/sql"
+	"strings"
+
+	"github.com/yourorg/arc-sdk/db"
 )
 
 // Session represents an agent session.
@@ -30,17 +33,27 @@ type Session struct {
 
 // SessionsStore manages session data in the database.
 type SessionsStore struct {
-	DB *sql.DB
+	DB db.Querier
 }
 
 // NewSessionsStore creates a new SessionsStore.
-func NewSessionsStore(db *sql.DB) *SessionsStore {
-	return &SessionsStore{DB: db}
+func NewSessionsStore(q db.Querier) *SessionsStore {
+	return &SessionsStore{DB: q}
 }
 
-// Upsert inserts or updates a session row.
+// Upsert inserts or updates a session row. sess.Tags is a comma-separated
+// list that replaces the session's tags.
 func (s *SessionsStore) Upsert(ctx context.Context, sess Session) error {
-	_, err := s.DB.ExecContext(ctx, `INSERT INTO sessions(
+	return db.InTx(ctx, s.DB, func(tx *sql.Tx) error {
+		if err := upsertSessionRow(ctx, tx, sess); err != nil {
+			return err
+		}
+		return NewTagsStore(tx).Set(ctx, SessionTags, sess.ID, SplitSessionTags(sess.Tags))
+	})
+}
+
+func upsertSessionRow(ctx context.Context, tx *sql.Tx, sess Session) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO sessions(
 		id, agent, cwd, project, branch, path, mod_ts, create_ts, lines, last_user, last_ts, tags, archived,
 		tmux_session_id, tmux_window_index, tmux_pane_index
 	) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
@@ -64,6 +77,35 @@ func (s *SessionsStore) Upsert(ctx context.Context, sess Session) error {
 	return err
 }
 
+// SplitSessionTags splits a comma-separated Session.Tags value into tags.
+func SplitSessionTags(tags string) []string {
+	if strings.TrimSpace(tags) == "" {
+		return nil
+	}
+	return normalizeTags(strings.Split(tags, ","))
+}
+
+// FindByTag returns the sessions tagged with tag or one of its descendants,
+// most recent first.
+func (s *SessionsStore) FindByTag(ctx context.Context, tag string) ([]Session, error) {
+	cond, args := tagMatch("t.name", NormalizeTag(tag))
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT id, agent, cwd, project, branch, path, mod_ts, create_ts, lines, last_user, last_ts, tags, archived,
+		       tmux_session_id, tmux_window_index, tmux_pane_index
+		FROM sessions
+		WHERE EXISTS (
+			SELECT 1 FROM session_tags st JOIN tags t ON t.id = st.tag_id
+			WHERE st.session_id = sessions.id AND `+cond+`
+		)
+		ORDER BY mod_ts DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return s.scanSessions(rows)
+}
+
 // FindLast returns the most recent session for an optional agent.
 func (s *SessionsStore) FindLast(ctx context.Context, agent string) (*Session, error) {
 	q := `SELECT id, agent, cwd, project, branch, path, mod_ts, create_ts, lines, last_user, last_ts, tags, archived,
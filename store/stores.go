@@ -0,0 +1,50 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/yourorg/arc-sdk/db"
+)
+
+// Stores groups the table stores bound to one Querier, typically a
+// transaction, so related writes can be committed atomically.
+type Stores struct {
+	Repos    *ReposStore
+	Analyses *RepoAnalysisStore
+	Sessions *SessionsStore
+	Deps     *DepsStore
+	Env      *EnvStore
+	Tags     *TagsStore
+}
+
+// NewStores returns every store bound to q.
+func NewStores(q db.Querier) *Stores {
+	return &Stores{
+		Repos:    NewReposStore(q),
+		Analyses: NewRepoAnalysisStore(q),
+		Sessions: NewSessionsStore(q),
+		Deps:     NewDepsStore(q),
+		Env:      NewEnvStore(q),
+		Tags:     NewTagsStore(q),
+	}
+}
+
+// WithTx runs fn with stores scoped to a new transaction on d, committing if
+// fn returns nil and rolling back otherwise.
+func WithTx(ctx context.Context, d *sql.DB, fn func(*Stores) error) error {
+	return db.WithTx(ctx, d, func(tx *sql.Tx) error {
+		return fn(NewStores(tx))
+	})
+}
+
+// WithTxOptions is like WithTx but accepts db.TxOptions (immediate locking,
+// busy retries, read-only). fn may run more than once when retrying.
+func WithTxOptions(ctx context.Context, d *sql.DB, opts db.TxOptions, fn func(*Stores) error) error {
+	return db.WithTxOptions(ctx, d, opts, func(_ context.Context, tx *sql.Tx) error {
+		return fn(NewStores(tx))
+	})
+}
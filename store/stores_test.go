@@ -0,0 +1,648 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourorg/arc-sdk/db"
+	"github.com/yourorg/arc-sdk/db/migrations"
+)
+
+func TestWithTxStores(t *testing.T) {
+	ctx := context.Background()
+	d, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d.Close()
+
+	repo := Repo{Name: "llm", URL: "https://github.com/simonw/llm", AddedAt: time.Now().Unix()}
+	deps := []Dependency{{Name: "click", Ecosystem: "pypi", DetectedAt: time.Now()}}
+
+	errAbort := errors.New("abort")
+	err = WithTx(ctx, d, func(s *Stores) error {
+		if err := s.Repos.Upsert(ctx, repo); err != nil {
+			return err
+		}
+		if err := s.Deps.UpsertDependencies(ctx, repo.Name, deps); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("WithTx error = %v, want %v", err, errAbort)
+	}
+	if n, _ := NewReposStore(d).Count(ctx); n != 0 {
+		t.Fatalf("rolled back tx left %d repos", n)
+	}
+
+	err = WithTx(ctx, d, func(s *Stores) error {
+		if err := s.Repos.Upsert(ctx, repo); err != nil {
+			return err
+		}
+		return s.Deps.UpsertDependencies(ctx, repo.Name, deps)
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	got, err := NewDepsStore(d).GetDependencies(ctx, repo.Name)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("GetDependencies = %v (%v), want 1 dependency", got, err)
+	}
+}
+
+func TestRepoAnalysisStore(t *testing.T) {
+	ctx := context.Background()
+	d, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d.Close()
+
+	s := NewStores(d)
+	now := time.Now()
+	for _, name := range []string{"llm", "datasette", "sqlite-utils"} {
+		if err := s.Repos.Upsert(ctx, Repo{Name: name, URL: "https://github.com/simonw/" + name, AddedAt: now.Unix()}); err != nil {
+			t.Fatalf("Upsert %s: %v", name, err)
+		}
+	}
+	record := func(repo, typ string, at time.Time, success bool, notes string) int64 {
+		t.Helper()
+		id, err := s.Analyses.Record(ctx, RepoAnalysis{
+			RepoName: repo, AnalysisType: typ, PromptTemplate: "explore", AnalyzedAt: at.Unix(),
+			AnalyzedBy: "claude", ContextFiles: []string{"README.md", "go.mod"}, Success: success, Notes: notes,
+		})
+		if err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+		return id
+	}
+	record("llm", "architecture", now.AddDate(0, 0, -200), true, "plugin hooks via pluggy")
+	latest := record("llm", "security", now.AddDate(0, 0, -1), true, "key storage review")
+	record("datasette", "architecture", now.AddDate(0, 0, -120), true, "asgi app")
+	record("datasette", "architecture", now.AddDate(0, 0, -2), false, "timed out")
+
+	a, err := s.Analyses.Latest(ctx, "llm", "")
+	if err != nil || a.ID != latest || len(a.ContextFiles) != 2 || !a.Success {
+		t.Fatalf("Latest = %+v, %v", a, err)
+	}
+	if h, _ := s.Analyses.History(ctx, "llm", AnalysisHistoryOptions{Type: "architecture"}); len(h) != 1 {
+		t.Fatalf("History by type = %d entries, want 1", len(h))
+	}
+	if found, err := s.Analyses.Search(ctx, "pluggy", AnalysisSearchOptions{}); err != nil || len(found) != 1 || found[0].RepoName != "llm" {
+		t.Fatalf("Search = %+v, %v", found, err)
+	}
+
+	repo, err := s.Repos.Get(ctx, "llm")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if repo.AnalysisCount != 2 || repo.LastAnalysisType != "security" || repo.LastAnalyzedBy != "claude" || repo.LastAnalyzedAt == 0 {
+		t.Fatalf("summary fields = %d %q %q %d", repo.AnalysisCount, repo.LastAnalysisType, repo.LastAnalyzedBy, repo.LastAnalyzedAt)
+	}
+
+	// The failed run does not count, and never-analyzed repos come first.
+	stale, err := s.Repos.NotAnalyzedSince(ctx, now.AddDate(0, 0, -90).Unix(), "")
+	if err != nil {
+		t.Fatalf("NotAnalyzedSince: %v", err)
+	}
+	if len(stale) != 2 || stale[0].Name != "sqlite-utils" || stale[1].Name != "datasette" {
+		t.Fatalf("NotAnalyzedSince = %v", stale)
+	}
+	if stale, _ := s.Repos.NotAnalyzedSince(ctx, now.AddDate(0, 0, -90).Unix(), "architecture"); len(stale) != 3 {
+		t.Fatalf("NotAnalyzedSince(architecture) = %d repos, want 3", len(stale))
+	}
+}
+
+func TestReposQuery(t *testing.T) {
+	ctx := context.Background()
+	d, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d.Close()
+
+	repos := NewReposStore(d)
+	seed := []Repo{
+		{Name: "llm", Platform: "GitHub", Owner: "simonw", Language: "Python", Stars: 900, Tags: []string{"ai", "cli"}, Topics: []string{"llm"}, Description: "CLI for large language models", AddedAt: 100},
+		{Name: "ollama", Platform: "GitHub", Owner: "ollama", Language: "Go", Stars: 5000, Tags: []string{"ai", "server"}, Description: "Run language models locally", AddedAt: 200, Archived: true},
+		{Name: "ripgrep", Platform: "GitHub", Owner: "BurntSushi", Language: "Rust", Stars: 5000, Tags: []string{"cli"}, Description: "Fast grep", AddedAt: 300},
+		{Name: "gitlab-runner", Platform: "GitLab", Owner: "gitlab-org", Language: "Go", Stars: 10, Description: "CI runner", AddedAt: 400},
+	}
+	for _, r := range seed {
+		r.URL = "https://example.com/" + r.Name
+		if err := repos.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert %s: %v", r.Name, err)
+		}
+	}
+	names := func(p *RepoPage) string {
+		var out []string
+		for _, r := range p.Repos {
+			out = append(out, r.Name)
+		}
+		return strings.Join(out, ",")
+	}
+	query := func(q RepoQuery) *RepoPage {
+		t.Helper()
+		p, err := repos.Query(ctx, q)
+		if err != nil {
+			t.Fatalf("Query(%+v): %v", q, err)
+		}
+		return p
+	}
+
+	if got := names(query(RepoQuery{TagsAll: []string{"ai", "cli"}})); got != "llm" {
+		t.Fatalf("TagsAll = %s", got)
+	}
+	if got := names(query(RepoQuery{TagsAny: []string{"server", "cli"}, Sort: SortName})); got != "llm,ollama,ripgrep" {
+		t.Fatalf("TagsAny = %s", got)
+	}
+	notArchived := false
+	if got := names(query(RepoQuery{Languages: []string{"Go"}, Archived: &notArchived})); got != "gitlab-runner" {
+		t.Fatalf("Go, not archived = %s", got)
+	}
+	if got := names(query(RepoQuery{Text: "language models", Added: TimeRange{Since: 150}})); got != "ollama" {
+		t.Fatalf("text with added range = %s", got)
+	}
+	if got := names(query(RepoQuery{TopicsAny: []string{"llm"}, Platforms: []string{"GitHub"}})); got != "llm" {
+		t.Fatalf("topics = %s", got)
+	}
+
+	// Page through ties on stars; the name tiebreak keeps pages disjoint.
+	var all []string
+	q := RepoQuery{Sort: SortStars, Desc: true, Limit: 1}
+	for {
+		p := query(q)
+		all = append(all, names(p))
+		if p.NextCursor == "" {
+			break
+		}
+		q.Cursor = p.NextCursor
+	}
+	if got := strings.Join(all, ","); got != "ollama,ripgrep,llm,gitlab-runner" {
+		t.Fatalf("paged by stars = %s", got)
+	}
+	first := query(RepoQuery{Text: "models OR grep", Limit: 2})
+	rest := query(RepoQuery{Text: "models OR grep", Limit: 2, Cursor: first.NextCursor})
+	if len(first.Repos) != 2 || len(rest.Repos) != 1 || rest.NextCursor != "" {
+		t.Fatalf("relevance pages = %s | %s", names(first), names(rest))
+	}
+	if _, err := repos.Query(ctx, RepoQuery{Sort: SortName, Cursor: q.Cursor}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for mismatched sort, got %v", err)
+	}
+}
+
+func TestNormalizedTags(t *testing.T) {
+	ctx := context.Background()
+	d, err := db.OpenWithOptions(":memory:", db.Options{SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d.Close()
+
+	// Rows written before tags were normalized.
+	var before []int
+	for v := 1; v < 15; v++ {
+		before = append(before, v)
+	}
+	if err := migrations.RunVersions(d, before...); err != nil {
+		t.Fatalf("RunVersions: %v", err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO external_repos(name, url, description, platform, owner, repo, topics, stars, forks, commit_count, added_at, tags)
+		 VALUES('llm', 'u1', '', '', '', '', '[]', 10, 0, 0, 1, '["ai","cli"]')`,
+		`INSERT INTO external_repos(name, url, description, platform, owner, repo, topics, stars, forks, commit_count, added_at, tags)
+		 VALUES('gopls', 'u2', '', '', '', '', '[]', 20, 0, 0, 2, 'lang/go, tools')`,
+		`INSERT INTO sessions(id, agent, cwd, project, branch, path, mod_ts, create_ts, last_user, last_ts, tags)
+		 VALUES('s1', 'claude', '', '', '', '/tmp/s1', 0, 0, '', 0, 'wip, lang/go')`,
+	} {
+		if _, err := d.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if err := migrations.RunMigrations(d); err != nil {
+		t.Fatalf("RunMigrations: %v", err)
+	}
+
+	s := NewStores(d)
+	repo, err := s.Repos.Get(ctx, "gopls")
+	if err != nil || strings.Join(repo.Tags, ",") != "lang/go,tools" {
+		t.Fatalf("migrated repo tags = %v, %v", repo, err)
+	}
+	if sess, _ := s.Sessions.Get(ctx, "s1"); sess.Tags != "wip,lang/go" {
+		t.Fatalf("migrated session tags = %q", sess.Tags)
+	}
+	if ids, _ := s.Tags.Find(ctx, SessionTags, "lang"); strings.Join(ids, ",") != "s1" {
+		t.Fatalf("sessions under lang = %v", ids)
+	}
+
+	// Bulk tagging is all or nothing.
+	if err := s.Tags.Tag(ctx, RepoTags, []string{"llm", "missing"}, "favorite"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected missing repo error, got %v", err)
+	}
+	if err := s.Tags.Tag(ctx, RepoTags, []string{"llm", "gopls"}, " lang / python ", "favorite"); err != nil {
+		t.Fatalf("Tag: %v", err)
+	}
+	found, _ := s.Repos.FindByTag(ctx, "lang")
+	if len(found) != 2 || found[0].Name != "gopls" {
+		t.Fatalf("FindByTag(lang) = %v", found)
+	}
+
+	if err := s.Tags.Rename(ctx, "lang", "language"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if err := s.Tags.Rename(ctx, "cli", "tools"); !errors.Is(err, ErrTagExists) {
+		t.Fatalf("expected ErrTagExists, got %v", err)
+	}
+	if err := s.Tags.Merge(ctx, "cli", "tools"); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	repo, _ = s.Repos.Get(ctx, "llm")
+	if got := strings.Join(repo.Tags, ","); got != "ai,language/python,favorite,tools" {
+		t.Fatalf("llm tags after rename and merge = %s", got)
+	}
+	if sess, _ := s.Sessions.Get(ctx, "s1"); sess.Tags != "wip,language/go" {
+		t.Fatalf("session tags after rename = %q", sess.Tags)
+	}
+	// The FTS index follows tag changes.
+	if hits, err := s.Repos.Search(ctx, "tools", SearchOptions{}); err != nil || len(hits) != 2 {
+		t.Fatalf("Search(tools) = %v, %v", hits, err)
+	}
+	if hits, _ := s.Repos.Search(ctx, "cli", SearchOptions{}); len(hits) != 0 {
+		t.Fatalf("stale FTS hits for cli: %v", hits)
+	}
+
+	if err := s.Tags.Untag(ctx, RepoTags, []string{"llm", "gopls"}, "favorite"); err != nil {
+		t.Fatalf("Untag: %v", err)
+	}
+	if counts, _ := s.Repos.ListTags(ctx); counts["favorite"] != 0 || counts["tools"] != 2 {
+		t.Fatalf("ListTags = %v", counts)
+	}
+}
+
+func TestUpdateFromClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	d, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d.Close()
+	repos := NewReposStore(d)
+
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"main.go":   "package main\n",
+		"README.md": "# tool\n\nA small tool.\n",
+		"COPYING":   "Redistribution and use in source and binary forms, with or without modification, are permitted.",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main", dir},
+		{"-C", dir, "add", "."},
+		{"-C", dir, "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	err = repos.Upsert(ctx, Repo{
+		Name: "tool", URL: "https://github.com/acme/tool", Description: "From the forge",
+		Cloned: true, ClonePath: dir, AddedAt: 1,
+	})
+	if err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	in, err := repos.UpdateFromClone(ctx, "tool", "")
+	if err != nil {
+		t.Fatalf("UpdateFromClone: %v", err)
+	}
+	repo, _ := repos.Get(ctx, "tool")
+	if repo.DefaultBranch != "main" || repo.CommitCount != 1 || repo.LastCommitAt != in.LastCommitAt ||
+		repo.Language != "Go" || repo.License != "BSD-2-Clause" {
+		t.Fatalf("repo after UpdateFromClone = %+v", repo)
+	}
+	// Guessed fields never replace values that are already set.
+	if repo.Description != "From the forge" || in.Description != "A small tool." {
+		t.Fatalf("description = %q, inspected %q", repo.Description, in.Description)
+	}
+	if _, err := repos.UpdateFromClone(ctx, "missing", dir); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestRepoListImportExport(t *testing.T) {
+	ctx := context.Background()
+	d, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d.Close()
+	repos := NewReposStore(d)
+	if err := repos.Upsert(ctx, Repo{Name: "llm", URL: "https://github.com/simonw/llm", AddedAt: 1}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	manifest := `
+version: 1
+repos:
+  - url: git@github.com:simonw/llm.git
+    description: CLI for LLMs
+    tags: [fav]
+  - url: not a url
+groups:
+  - name: Databases
+    groups:
+      - name: kv
+        repos:
+          - url: https://github.com/etcd-io/bbolt
+`
+	res, err := repos.Import(ctx, strings.NewReader(manifest), ImportOptions{Format: FormatYAML})
+	if err != nil {
+		t.Fatalf("Import yaml: %v", err)
+	}
+	if strings.Join(res.Added, ",") != "bbolt" || strings.Join(res.Updated, ",") != "llm" || len(res.Invalid) != 1 {
+		t.Fatalf("yaml import = %+v", res)
+	}
+	if llm, _ := repos.Get(ctx, "llm"); llm.Description != "CLI for LLMs" || strings.Join(llm.Tags, ",") != "fav" {
+		t.Fatalf("merged llm = %+v", llm)
+	}
+
+	awesome := "# Awesome\n\n## Contents\n\n- [Tools](#command-line)\n\n## Command Line\n\n" +
+		"- [fzf](https://github.com/junegunn/fzf) - A **fuzzy** finder.\n" +
+		"- [site](https://example.com/a/b) - not a forge\n\n" +
+		"### Sub Section\n\n* [bbolt](https://github.com/etcd-io/bbolt): KV store\n"
+	res, err = repos.Import(ctx, strings.NewReader(awesome), ImportOptions{Format: FormatMarkdown})
+	if err != nil || strings.Join(res.Added, ",") != "fzf" || strings.Join(res.Updated, ",") != "bbolt" {
+		t.Fatalf("markdown import = %+v, %v", res, err)
+	}
+	if fzf, _ := repos.Get(ctx, "fzf"); fzf.Description != "A fuzzy finder." || strings.Join(fzf.Tags, ",") != "command-line" {
+		t.Fatalf("imported fzf = %+v", fzf)
+	}
+	if bbolt, _ := repos.Get(ctx, "bbolt"); strings.Join(bbolt.Tags, ",") != "Databases/kv,command-line/sub-section" ||
+		bbolt.Description != "KV store" {
+		t.Fatalf("merged bbolt = %+v", bbolt)
+	}
+
+	stars := `[
+		{"starred_at": "2024-01-01T00:00:00Z", "repo": {"html_url": "https://github.com/junegunn/fzf", "stargazers_count": 60000}},
+		{"html_url": "https://github.com/BurntSushi/ripgrep", "description": "rg", "language": "Rust",
+		 "stargazers_count": 5, "license": {"spdx_id": "Unlicense"}}
+	]`
+	opts := ImportOptions{Format: FormatGitHubStars, Tags: []string{"starred"}, DryRun: true}
+	res, err = repos.Import(ctx, strings.NewReader(stars), opts)
+	if err != nil || strings.Join(res.Added, ",") != "ripgrep" || strings.Join(res.Updated, ",") != "fzf" {
+		t.Fatalf("dry-run stars import = %+v, %v", res, err)
+	}
+	if n, _ := repos.Count(ctx); n != 3 {
+		t.Fatalf("dry run wrote repos: count %d", n)
+	}
+	opts.DryRun = false
+	if _, err := repos.Import(ctx, strings.NewReader(stars), opts); err != nil {
+		t.Fatalf("Import stars: %v", err)
+	}
+	if rg, _ := repos.Get(ctx, "ripgrep"); rg.Stars != 5 || rg.License != "Unlicense" || rg.Owner != "BurntSushi" ||
+		strings.Join(rg.Tags, ",") != "starred" {
+		t.Fatalf("imported ripgrep = %+v", rg)
+	}
+
+	var md strings.Builder
+	if err := repos.Export(ctx, &md, FormatMarkdown); err != nil {
+		t.Fatalf("Export markdown: %v", err)
+	}
+	if !strings.Contains(md.String(), "## command-line\n\n- [fzf](https://github.com/junegunn/fzf) - A fuzzy finder.\n") ||
+		!strings.Contains(md.String(), "## Databases\n\n### kv\n\n- [bbolt]") {
+		t.Fatalf("markdown export:\n%s", md.String())
+	}
+
+	// Every format round-trips into an empty database.
+	for _, format := range []RepoListFormat{FormatYAML, FormatJSON, FormatMarkdown, FormatGitHubStars} {
+		var buf strings.Builder
+		if err := repos.Export(ctx, &buf, format); err != nil {
+			t.Fatalf("Export %s: %v", format, err)
+		}
+		d2, err := db.Open(":memory:")
+		if err != nil {
+			t.Fatalf("db.Open: %v", err)
+		}
+		res, err := NewReposStore(d2).Import(ctx, strings.NewReader(buf.String()), ImportOptions{Format: format})
+		d2.Close()
+		if err != nil || len(res.Added) != 4 || len(res.Invalid) != 0 {
+			t.Fatalf("re-import %s = %+v, %v\n%s", format, res, err, buf.String())
+		}
+	}
+
+	// Markdown keeps names, and a section's subsections follow it.
+	d3, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d3.Close()
+	nested := NewReposStore(d3)
+	for _, r := range []Repo{
+		{Name: "renamed", URL: "https://github.com/x/one", Tags: []string{"a/b"}, AddedAt: 1},
+		{Name: "two", URL: "https://github.com/x/two", Tags: []string{"a/b-c"}, AddedAt: 1},
+		{Name: "three", URL: "https://github.com/x/three", Tags: []string{"a/b/d"}, AddedAt: 1},
+	} {
+		if err := nested.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert %s: %v", r.Name, err)
+		}
+	}
+	md.Reset()
+	if err := nested.Export(ctx, &md, FormatMarkdown); err != nil {
+		t.Fatalf("Export markdown: %v", err)
+	}
+	if n := strings.Count(md.String(), "### b\n"); n != 1 {
+		t.Fatalf("heading b opened %d times:\n%s", n, md.String())
+	}
+	d4, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d4.Close()
+	reimported := NewReposStore(d4)
+	if _, err := reimported.Import(ctx, strings.NewReader(md.String()), ImportOptions{Format: FormatMarkdown}); err != nil {
+		t.Fatalf("re-import markdown: %v", err)
+	}
+	for name, tag := range map[string]string{"renamed": "a/b", "two": "a/b-c", "three": "a/b/d"} {
+		if r, err := reimported.Get(ctx, name); err != nil || strings.Join(r.Tags, ",") != tag {
+			t.Fatalf("re-imported %s = %+v, %v\n%s", name, r, err, md.String())
+		}
+	}
+}
+
+func TestRepoDuplicates(t *testing.T) {
+	ctx := context.Background()
+	d, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d.Close()
+	repos := NewReposStore(d)
+	for _, r := range []Repo{
+		{Name: "y", URL: "https://github.com/x/y", AddedAt: 1, AccessCount: 2, Tags: []string{"go"}, Notes: "first"},
+		{Name: "y-ssh", URL: "git@github.com:x/y.git", AddedAt: 2, AccessCount: 3, Tags: []string{"cli"}, Notes: "second"},
+		{Name: "z", URL: "https://github.com/x/z", AddedAt: 3},
+	} {
+		if err := repos.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert %s: %v", r.Name, err)
+		}
+	}
+
+	groups, err := repos.FindDuplicates(ctx)
+	if err != nil {
+		t.Fatalf("FindDuplicates: %v", err)
+	}
+	if len(groups) != 1 || groups[0].CanonicalURL != "https://github.com/x/y" ||
+		len(groups[0].Repos) != 2 || groups[0].Repos[0].Name != "y" {
+		t.Fatalf("FindDuplicates = %+v", groups)
+	}
+
+	if err := repos.RecordEnrichment(ctx, RepoEnrichment{RepoName: "y-ssh", Provider: "github.com", ETag: "e1", EnrichedAt: 5, CheckedAt: 5}); err != nil {
+		t.Fatalf("RecordEnrichment: %v", err)
+	}
+	if err := repos.Merge(ctx, "y", "y-ssh"); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if e, err := repos.Enrichment(ctx, "y"); err != nil || e.ETag != "e1" {
+		t.Fatalf("Enrichment after merge = %+v, %v", e, err)
+	}
+	y, err := repos.Get(ctx, "y")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if y.AccessCount != 5 || strings.Join(y.Tags, ",") != "go,cli" || y.Notes != "first\n\nsecond" || y.AddedAt != 1 {
+		t.Fatalf("merged = %+v", y)
+	}
+	if _, err := repos.Get(ctx, "y-ssh"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("duplicate not deleted: %v", err)
+	}
+	if r, err := repos.GetByURL(ctx, "git@github.com:x/y.git"); err != nil || r.Name != "y" {
+		t.Fatalf("GetByURL(ssh) = %+v, %v", r, err)
+	}
+
+	// A rename leaves a redirect from the old location.
+	if err := repos.Move(ctx, "z", "https://github.com/x/z2"); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if r, err := repos.GetByURL(ctx, "https://github.com/x/z.git"); err != nil || r.Name != "z" || r.Repo != "z2" {
+		t.Fatalf("GetByURL(old) = %+v, %v", r, err)
+	}
+	if err := repos.Upsert(ctx, Repo{Name: "z-old", URL: "https://github.com/x/z", AddedAt: 4}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	groups, err = repos.FindDuplicates(ctx)
+	if err != nil || len(groups) != 1 || groups[0].CanonicalURL != "https://github.com/x/z2" {
+		t.Fatalf("FindDuplicates after move = %+v, %v", groups, err)
+	}
+}
+
+func TestRepoUsage(t *testing.T) {
+	ctx := context.Background()
+	d, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer d.Close()
+	repos := NewReposStore(d)
+	now := time.Now()
+	hour, day := int64(3600), int64(86400)
+	for _, r := range []Repo{
+		// ×4: 8
+		{Name: "ripgrep", URL: "https://github.com/BurntSushi/ripgrep", Language: "Rust", Tags: []string{"cli"},
+			AccessCount: 2, LastOpenedAt: now.Unix() - 60, AddedAt: 1},
+		// ×¼: 5
+		{Name: "fzf", URL: "https://github.com/junegunn/fzf", Language: "Go", Tags: []string{"cli"},
+			AccessCount: 20, LastOpenedAt: now.Unix() - 30*day, AddedAt: 2},
+		// ×2: 6
+		{Name: "bbolt", URL: "https://github.com/etcd-io/bbolt", Language: "Go", Tags: []string{"db"},
+			AccessCount: 3, LastOpenedAt: now.Unix() - 2*hour, AddedAt: 3},
+		{Name: "old-tool", URL: "https://github.com/x/old-tool", AddedAt: now.Unix() - 400*day, LastCommitAt: now.Unix() - 500*day},
+		{Name: "new-tool", URL: "https://github.com/x/new-tool", AddedAt: now.Unix() - day},
+	} {
+		if err := repos.Upsert(ctx, r); err != nil {
+			t.Fatalf("Upsert %s: %v", r.Name, err)
+		}
+	}
+	names := func(scores []RepoScore) string {
+		var out []string
+		for _, s := range scores {
+			out = append(out, s.Repo.Name)
+		}
+		return strings.Join(out, ",")
+	}
+
+	frecent, err := repos.Frecent(ctx, UsageOptions{Now: now})
+	if err != nil || names(frecent) != "ripgrep,bbolt,fzf" || frecent[0].Score != 8 {
+		t.Fatalf("Frecent = %+v, %v", frecent, err)
+	}
+	page, err := repos.Query(ctx, RepoQuery{Sort: SortFrecency, Desc: true, Limit: 2})
+	if err != nil || len(page.Repos) != 2 || page.Repos[0].Name != "ripgrep" || page.Repos[1].Name != "bbolt" {
+		t.Fatalf("Query by frecency = %+v, %v", page, err)
+	}
+	// Later pages score as of the first, whatever Now they pass.
+	var paged []string
+	q := RepoQuery{Sort: SortFrecency, Desc: true, Limit: 2, Now: now}
+	for {
+		page, err := repos.Query(ctx, q)
+		if err != nil {
+			t.Fatalf("Query by frecency: %v", err)
+		}
+		for _, r := range page.Repos {
+			paged = append(paged, r.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor, q.Now = page.NextCursor, now.Add(48*time.Hour)
+	}
+	if got := strings.Join(paged, ","); got != "ripgrep,bbolt,fzf,new-tool,old-tool" {
+		t.Fatalf("paged by frecency = %s", got)
+	}
+
+	stale, err := repos.ArchiveCandidates(ctx, now.Unix()-365*day)
+	if err != nil || len(stale) != 1 || stale[0].Name != "old-tool" {
+		t.Fatalf("ArchiveCandidates = %+v, %v", stale, err)
+	}
+
+	byLang, err := repos.MostUsed(ctx, UsageByLanguage, UsageOptions{Now: now, Limit: 1})
+	if err != nil || len(byLang) != 2 || byLang[0].Key != "Go" || byLang[0].Opens != 23 ||
+		names(byLang[0].Repos) != "bbolt" || byLang[1].Key != "Rust" {
+		t.Fatalf("MostUsed(language) = %+v, %v", byLang, err)
+	}
+	byTag, err := repos.MostUsed(ctx, UsageByTag, UsageOptions{Now: now})
+	if err != nil || len(byTag) != 2 || byTag[0].Key != "cli" || names(byTag[0].Repos) != "ripgrep,fzf" {
+		t.Fatalf("MostUsed(tag) = %+v, %v", byTag, err)
+	}
+
+	// old-tool is the better match for "o", but bbolt is used.
+	jump, err := repos.Jump(ctx, "o", UsageOptions{Now: now})
+	if err != nil || names(jump) != "bbolt,old-tool,new-tool" {
+		t.Fatalf("Jump(o) = %v, %v", names(jump), err)
+	}
+	jump, err = repos.Jump(ctx, "rg", UsageOptions{Now: now})
+	if err != nil || names(jump) != "ripgrep" {
+		t.Fatalf("Jump(rg) = %v, %v", names(jump), err)
+	}
+	jump, err = repos.Jump(ctx, "etcd/bb", UsageOptions{Now: now, Limit: 1})
+	if err != nil || names(jump) != "bbolt" {
+		t.Fatalf("Jump(etcd/bb) = %v, %v", names(jump), err)
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yourorg/arc-sdk/db"
+)
+
+var (
+	// ErrTagNotFound is returned when renaming or merging an unknown tag.
+	ErrTagNotFound = errors.New("store: tag not found")
+	// ErrTagExists is returned by Rename when the new name is taken; use
+	// Merge to combine the tags instead.
+	ErrTagExists = errors.New("store: tag already exists")
+)
+
+// TagKind selects the entities a tag operation applies to.
+type TagKind string
+
+const (
+	RepoTags    TagKind = "repo"
+	SessionTags TagKind = "session"
+)
+
+// tagTable describes the join table linking one kind of entity to tags.
+type tagTable struct {
+	join, idColumn string
+	// owner and ownerKey name the tagged entity's table and key.
+	owner, ownerKey string
+}
+
+var tagTables = map[TagKind]tagTable{
+	RepoTags:    {join: "repo_tags", idColumn: "repo_name", owner: "external_repos", ownerKey: "name"},
+	SessionTags: {join: "session_tags", idColumn: "session_id", owner: "sessions", ownerKey: "id"},
+}
+
+func tableFor(kind TagKind) (tagTable, error) {
+	t, ok := tagTables[kind]
+	if !ok {
+		return t, fmt.Errorf("unknown tag kind %q", kind)
+	}
+	return t, nil
+}
+
+// TagCount reports how many entities carry a tag.
+type TagCount struct {
+	Name     string
+	Repos    int
+	Sessions int
+}
+
+// TagsStore manages tags on repos and sessions. Tags are hierarchical paths
+// such as "lang/go": finding "lang" also finds its descendants, and renaming
+// or merging a tag carries its descendants along.
+type TagsStore struct {
+	DB db.Querier
+}
+
+// NewTagsStore creates a new TagsStore.
+func NewTagsStore(q db.Querier) *TagsStore {
+	return &TagsStore{DB: q}
+}
+
+// NormalizeTag trims whitespace around a tag and each of its "/"-separated
+// segments and drops empty segments, so " lang / go/" becomes "lang/go".
+func NormalizeTag(tag string) string {
+	var parts []string
+	for _, p := range strings.Split(tag, "/") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// normalizeTags normalizes tags, dropping empty and repeated ones.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t = NormalizeTag(t); t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// tagMatch returns a condition on column matching tag or any descendant,
+// using a range scan rather than LIKE so tags need no escaping.
+func tagMatch(column, tag string) (string, []any) {
+	// '0' is the character after '/'.
+	return "(" + column + " = ? OR (" + column + " >= ? AND " + column + " < ?))", []any{tag, tag + "/", tag + "0"}
+}
+
+// tagID returns the id of the tag named name, creating it if needed.
+func tagID(ctx context.Context, tx *sql.Tx, name string) (int64, error) {
+	if _, err := tx.ExecContext(ctx, `INSERT INTO tags(name) VALUES(?) ON CONFLICT(name) DO NOTHING`, name); err != nil {
+		return 0, fmt.Errorf("create tag %q: %w", name, err)
+	}
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM tags WHERE name = ?`, name).Scan(&id)
+	return id, err
+}
+
+// Tag adds every tag to every entity in one transaction. It fails without
+// changes if any entity does not exist.
+func (s *TagsStore) Tag(ctx context.Context, kind TagKind, ids []string, tags ...string) error {
+	t, err := tableFor(kind)
+	if err != nil {
+		return err
+	}
+	tags = normalizeTags(tags)
+	return db.InTx(ctx, s.DB, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			var n int
+			if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+t.owner+` WHERE `+t.ownerKey+` = ?`, id).Scan(&n); err != nil {
+				return err
+			}
+			if n == 0 {
+				return fmt.Errorf("tag %s %q: %w", kind, id, sql.ErrNoRows)
+			}
+		}
+		for _, tag := range tags {
+			tid, err := tagID(ctx, tx, tag)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO `+t.join+`(`+t.idColumn+`, tag_id) VALUES(?, ?)`, id, tid); err != nil {
+					return fmt.Errorf("tag %s %q: %w", kind, id, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Untag removes every tag from every entity in one transaction. Only the
+// exact tags are removed, not their descendants.
+func (s *TagsStore) Untag(ctx context.Context, kind TagKind, ids []string, tags ...string) error {
+	t, err := tableFor(kind)
+	if err != nil {
+		return err
+	}
+	tags = normalizeTags(tags)
+	return db.InTx(ctx, s.DB, func(tx *sql.Tx) error {
+		for _, tag := range tags {
+			for _, id := range ids {
+				_, err := tx.ExecContext(ctx, `
+					DELETE FROM `+t.join+`
+					WHERE `+t.idColumn+` = ? AND tag_id = (SELECT id FROM tags WHERE name = ?)
+				`, id, tag)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Set replaces an entity's tags, keeping the given order.
+func (s *TagsStore) Set(ctx context.Context, kind TagKind, id string, tags []string) error {
+	t, err := tableFor(kind)
+	if err != nil {
+		return err
+	}
+	return db.InTx(ctx, s.DB, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+t.join+` WHERE `+t.idColumn+` = ?`, id); err != nil {
+			return err
+		}
+		for _, tag := range normalizeTags(tags) {
+			tid, err := tagID(ctx, tx, tag)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO `+t.join+`(`+t.idColumn+`, tag_id) VALUES(?, ?)`, id, tid); err != nil {
+				return fmt.Errorf("tag %s %q: %w", kind, id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// TagsOf returns an entity's tags in the order they were added.
+func (s *TagsStore) TagsOf(ctx context.Context, kind TagKind, id string) ([]string, error) {
+	t, err := tableFor(kind)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT tags.name FROM `+t.join+` j JOIN tags ON tags.id = j.tag_id
+		WHERE j.`+t.idColumn+` = ?
+		ORDER BY j.rowid
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanStrings(rows)
+}
+
+// Find returns the ids of entities tagged with tag or any of its
+// descendants, sorted.
+func (s *TagsStore) Find(ctx context.Context, kind TagKind, tag string) ([]string, error) {
+	t, err := tableFor(kind)
+	if err != nil {
+		return nil, err
+	}
+	cond, args := tagMatch("tags.name", NormalizeTag(tag))
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT DISTINCT j.`+t.idColumn+` FROM `+t.join+` j JOIN tags ON tags.id = j.tag_id
+		WHERE `+cond+`
+		ORDER BY 1
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanStrings(rows)
+}
+
+// List returns the tags at or below prefix (all tags if empty) with usage
+// counts, sorted by name.
+func (s *TagsStore) List(ctx context.Context, prefix string) ([]TagCount, error) {
+	query := `
+		SELECT name,
+		       (SELECT COUNT(*) FROM repo_tags WHERE tag_id = tags.id),
+		       (SELECT COUNT(*) FROM session_tags WHERE tag_id = tags.id)
+		FROM tags`
+	var args []any
+	if prefix = NormalizeTag(prefix); prefix != "" {
+		var cond string
+		cond, args = tagMatch("name", prefix)
+		query += " WHERE " + cond
+	}
+	rows, err := s.DB.QueryContext(ctx, query+" ORDER BY name", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []TagCount
+	for rows.Next() {
+		var c TagCount
+		if err := rows.Scan(&c.Name, &c.Repos, &c.Sessions); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// Rename renames a tag and its descendants ("lang" to "language" also turns
+// "lang/go" into "language/go"). It returns ErrTagExists if any new name is
+// already in use.
+func (s *TagsStore) Rename(ctx context.Context, from, to string) error {
+	return s.move(ctx, from, to, false)
+}
+
+// Merge moves every use of from, and of its descendants, onto into (created
+// if needed) and deletes from. Descendants map onto matching paths below
+// into, merging with any that exist.
+func (s *TagsStore) Merge(ctx context.Context, from, into string) error {
+	return s.move(ctx, from, into, true)
+}
+
+func (s *TagsStore) move(ctx context.Context, from, to string, merge bool) error {
+	from, to = NormalizeTag(from), NormalizeTag(to)
+	if from == "" || to == "" {
+		return errors.New("tag names must not be empty")
+	}
+	if from == to {
+		return nil
+	}
+	if strings.HasPrefix(to, from+"/") {
+		return fmt.Errorf("cannot move tag %q below itself", from)
+	}
+	return db.InTx(ctx, s.DB, func(tx *sql.Tx) error {
+		cond, args := tagMatch("name", from)
+		rows, err := tx.QueryContext(ctx, `SELECT id, name FROM tags WHERE `+cond+` ORDER BY name`, args...)
+		if err != nil {
+			return err
+		}
+		type tagRow struct {
+			id   int64
+			name string
+		}
+		var moving []tagRow
+		for rows.Next() {
+			var r tagRow
+			if err := rows.Scan(&r.id, &r.name); err != nil {
+				rows.Close()
+				return err
+			}
+			moving = append(moving, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		// from may exist only as the parent of other tags.
+		if len(moving) == 0 {
+			return fmt.Errorf("%w: %s", ErrTagNotFound, from)
+		}
+
+		for _, m := range moving {
+			target := to + strings.TrimPrefix(m.name, from)
+			var existing int64
+			err := tx.QueryRowContext(ctx, `SELECT id FROM tags WHERE name = ?`, target).Scan(&existing)
+			if errors.Is(err, sql.ErrNoRows) {
+				if _, err := tx.ExecContext(ctx, `UPDATE tags SET name = ? WHERE id = ?`, target, m.id); err != nil {
+					return fmt.Errorf("rename tag %q: %w", m.name, err)
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if !merge {
+				return fmt.Errorf("%w: %s", ErrTagExists, target)
+			}
+			if err := mergeTagInto(ctx, tx, m.id, existing); err != nil {
+				return fmt.Errorf("merge tag %q into %q: %w", m.name, target, err)
+			}
+		}
+		return nil
+	})
+}
+
+// mergeTagInto re-points every use of tag id from onto id into, then
+// deletes from.
+func mergeTagInto(ctx context.Context, tx *sql.Tx, from, into int64) error {
+	for _, t := range tagTables {
+		_, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO `+t.join+`(`+t.idColumn+`, tag_id)
+			SELECT `+t.idColumn+`, ? FROM `+t.join+` WHERE tag_id = ? ORDER BY rowid
+		`, into, from)
+		if err != nil {
+			return err
+		}
+	}
+	// item_tags has no unique key, so skip items that already carry into.
+	_, err := tx.ExecContext(ctx, `
+		UPDATE item_tags SET tag_id = ?
+		WHERE tag_id = ? AND item_id NOT IN (SELECT item_id FROM item_tags WHERE tag_id = ?)
+	`, into, from, into)
+	if err != nil {
+		return err
+	}
+	// Remaining uses of from are removed by ON DELETE CASCADE.
+	_, err = tx.ExecContext(ctx, `DELETE FROM tags WHERE id = ?`, from)
+	return err
+}
+
+func scanStrings(rows *sql.Rows) ([]string, error) {
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
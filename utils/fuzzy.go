@@ -0,0 +1,73 @@
+// Copyright (c) 2025 Arc Engineering
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FuzzyScore reports whether pattern matches text, ignoring case, and how
+// well, from 1 for an exact match down towards 0. Matches rank by kind:
+// exact, then prefix, then a substring starting at a word boundary, then any
+// substring, then the pattern's characters in order with gaps. Within a kind,
+// matches covering more of the text (or, with gaps, spread less) score higher.
+func FuzzyScore(pattern, text string) (float64, bool) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(strings.ToLower(text))
+	if len(p) == 0 {
+		return 0, false
+	}
+	if string(p) == string(t) {
+		return 1, true
+	}
+	coverage := float64(len(p)) / float64(len(t))
+
+	if i := strings.Index(string(t), string(p)); i >= 0 {
+		if i == 0 {
+			return 0.8 + 0.19*coverage, true
+		}
+		// Prefer a later occurrence at a boundary, e.g. "cli" in "clip-cli".
+		for i := 1; i+len(p) <= len(t); i++ {
+			if wordStart(t, i) && string(t[i:i+len(p)]) == string(p) {
+				return 0.6 + 0.19*coverage, true
+			}
+		}
+		return 0.4 + 0.19*coverage, true
+	}
+
+	// The shortest window holding p as a subsequence.
+	span := 0
+	for start := range t {
+		if t[start] != p[0] {
+			continue
+		}
+		j := 1
+		end := start
+		for k := start + 1; k < len(t) && j < len(p); k++ {
+			if t[k] == p[j] {
+				j++
+				end = k
+			}
+		}
+		if j < len(p) {
+			break
+		}
+		if w := end - start + 1; span == 0 || w < span {
+			span = w
+		}
+	}
+	if span == 0 {
+		return 0, false
+	}
+	return 0.39 * float64(len(p)) / float64(span), true
+}
+
+// wordStart reports whether t[i] begins a word: it follows a separator or
+// digit/letter change.
+func wordStart(t []rune, i int) bool {
+	prev := t[i-1]
+	return !unicode.IsLetter(prev) && !unicode.IsDigit(prev) ||
+		unicode.IsDigit(prev) != unicode.IsDigit(t[i])
+}